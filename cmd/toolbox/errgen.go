@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rlapenok/toolbox/errors"
+	"github.com/rlapenok/toolbox/errors/catalog"
+)
+
+// runErrgen - generate Go constructors and a Markdown reference from an error catalog
+func runErrgen(args []string) error {
+	flags := flag.NewFlagSet("errgen", flag.ContinueOnError)
+	catalogPath := flags.String("catalog", "errors.yaml", "path to the YAML or JSON error catalog")
+	pkg := flags.String("package", "", "name of the generated package (overrides the catalog)")
+	out := flags.String("out", "errors_gen.go", "path of the generated Go file")
+	doc := flags.String("doc", "", "path of the generated Markdown reference (skipped if empty)")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	c, err := catalog.Load(*catalogPath)
+	if err != nil {
+		return err
+	}
+
+	source, err := catalog.GenerateGo(c, *pkg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(*out, source, 0o644); err != nil {
+		return errors.Wrap(err, errors.Internal, err.Error())
+	}
+	fmt.Fprintf(os.Stderr, "generated %d errors into %s\n", len(c.Errors), *out)
+
+	if *doc != "" {
+		if err := os.WriteFile(*doc, catalog.GenerateMarkdown(c), 0o644); err != nil {
+			return errors.Wrap(err, errors.Internal, err.Error())
+		}
+		fmt.Fprintf(os.Stderr, "generated reference into %s\n", *doc)
+	}
+
+	return nil
+}
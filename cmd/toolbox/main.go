@@ -0,0 +1,58 @@
+// Command toolbox - command line utilities of the toolbox
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rlapenok/toolbox/errors"
+)
+
+// command - subcommand of the toolbox
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+// commands - available subcommands
+var commands = []command{
+	{
+		name:        "errgen",
+		description: "generate Go constructors and a Markdown reference from an error catalog",
+		run:         runErrgen,
+	},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				message := err.Error()
+				if tbErr, ok := errors.From(err); ok {
+					message = tbErr.Message()
+				}
+				fmt.Fprintf(os.Stderr, "toolbox %s: %s\n", cmd.name, message)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "toolbox: unknown command %q\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: toolbox <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.description)
+	}
+}
@@ -27,3 +27,44 @@ type PoolConfig interface {
 	GetMaxConnKeepAliveCount() int
 	GetMaxConnKeepAliveInterval() time.Duration
 }
+
+// SSLPasswordConfig - optional interface of ConnectionConfig for the password of an encrypted client key
+type SSLPasswordConfig interface {
+	GetSSLPassword() string
+}
+
+// HealthCheckConfig - optional interface of PoolConfig for the period of idle connection health checks
+type HealthCheckConfig interface {
+	GetHealthCheckPeriod() time.Duration
+}
+
+// ConnectTimeoutConfig - optional interface of PoolConfig for the timeout of establishing a connection
+type ConnectTimeoutConfig interface {
+	GetConnectTimeout() time.Duration
+}
+
+// ApplicationNameConfig - optional interface of PoolConfig for the application_name of the connections
+type ApplicationNameConfig interface {
+	GetApplicationName() string
+}
+
+// QueryExecModeConfig - optional interface of PoolConfig for the query exec mode (see QueryExecMode constants)
+type QueryExecModeConfig interface {
+	GetQueryExecMode() string
+}
+
+// Query exec modes, see pgx.QueryExecMode
+const (
+	// QueryExecModeCacheStatement - prepare and cache statements (pgx default)
+	QueryExecModeCacheStatement = "cache_statement"
+	// QueryExecModeCacheDescribe - cache statement descriptions, execute with the extended protocol
+	QueryExecModeCacheDescribe = "cache_describe"
+	// QueryExecModeDescribeExec - describe and execute every query without caching
+	QueryExecModeDescribeExec = "describe_exec"
+	// QueryExecModeExec - execute with the extended protocol without prepared statements
+	QueryExecModeExec = "exec"
+	// QueryExecModeSimpleProtocol - execute with the simple protocol without prepared statements
+	QueryExecModeSimpleProtocol = "simple_protocol"
+	// QueryExecModePgBouncer - alias of QueryExecModeSimpleProtocol, compatible with PgBouncer transaction pooling
+	QueryExecModePgBouncer = "pgbouncer"
+)
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"sync"
+
+	"github.com/rlapenok/toolbox/errors"
+)
+
+// constraints - registered domain errors by the constraint name
+var constraints = struct {
+	sync.RWMutex
+	byName map[string]*ConstraintError
+}{byName: map[string]*ConstraintError{}}
+
+// ConstraintError - domain error returned by MapError when the constraint is violated.
+// The table and the constraint name stay in the internal details.
+type ConstraintError struct {
+	code     errors.Code
+	reason   errors.Reason
+	message  string
+	field    string
+	messages map[string]string
+}
+
+// NewConstraintError - create new domain error of a constraint with the reason and the message,
+// the code is taken from the generic mapping of the violation (e.g. Conflict for unique violations)
+func NewConstraintError(reason errors.Reason, message string) *ConstraintError {
+	return &ConstraintError{
+		reason:   reason,
+		message:  message,
+		messages: map[string]string{},
+	}
+}
+
+// WithCode - override the code of the generic mapping
+func (c *ConstraintError) WithCode(code errors.Code) *ConstraintError {
+	c.code = code
+
+	return c
+}
+
+// WithField - set the field of the request the constraint refers to, it's reported as a field violation
+func (c *ConstraintError) WithField(field string) *ConstraintError {
+	c.field = field
+
+	return c
+}
+
+// WithLocaleMessage - add the localized message
+func (c *ConstraintError) WithLocaleMessage(locale string, message string) *ConstraintError {
+	c.messages[locale] = message
+
+	return c
+}
+
+// RegisterConstraint - register the domain error of the constraint, e.g. "users_email_key".
+// Registering the same name again replaces the error.
+func RegisterConstraint(name string, constraintErr *ConstraintError) {
+	constraints.Lock()
+	defer constraints.Unlock()
+
+	constraints.byName[name] = constraintErr
+}
+
+// UnregisterConstraint - remove the domain error of the constraint
+func UnregisterConstraint(name string) {
+	constraints.Lock()
+	defer constraints.Unlock()
+
+	delete(constraints.byName, name)
+}
+
+// lookupConstraint - get the domain error of the constraint
+func lookupConstraint(name string) (*ConstraintError, bool) {
+	if name == "" {
+		return nil, false
+	}
+
+	constraints.RLock()
+	defer constraints.RUnlock()
+
+	constraintErr, ok := constraints.byName[name]
+
+	return constraintErr, ok
+}
+
+// toError - build the toolbox error of the violation from the generic mapping
+func (c *ConstraintError) toError(err error, generic *errors.Error) *errors.Error {
+	code := c.code
+	if code == 0 {
+		code = generic.Code()
+	}
+
+	details := errors.NewDetails()
+	for locale, message := range c.messages {
+		details.WithLocaleMessage(locale, message)
+	}
+
+	if c.field != "" {
+		violation := errors.NewFieldViolation(c.field, c.message).
+			WithRule(string(c.reason), "")
+		for locale, message := range c.messages {
+			violation.WithLocaleDescription(locale, message)
+		}
+		details.WithViolation(violation)
+	}
+
+	tbErr := errors.Wrap(err, code, c.message).
+		WithReason(c.reason).
+		WithDetails(details).
+		WithInternalDetails(generic.InternalDetails())
+
+	// keep the gRPC code of the generic mapping (e.g. FailedPrecondition of foreign key violations)
+	if c.code == 0 {
+		tbErr.WithGRPCCode(generic.ToGRPCCode())
+	}
+
+	return tbErr
+}
@@ -1,120 +1,228 @@
 package postgres
 
 import (
+	"context"
 	stderrs "errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/rlapenok/toolbox/errors"
+	"google.golang.org/grpc/codes"
 )
 
-// MapError converts database/pgx errors into toolbox errors with appropriate codes and reasons
+// MapError converts database/pgx errors into toolbox errors with appropriate codes and reasons.
+// PostgreSQL internals (schema, table, constraint, routine, etc.) are kept in the internal details
+// and never reach the client.
 func MapError(err error) *errors.Error {
 	if err == nil {
 		return nil
 	}
 
 	// if it's already a toolbox error, pass it through
-	if tbErr, ok := err.(*errors.Error); ok {
+	if tbErr, ok := errors.From(err); ok {
 		return tbErr
 	}
 
+	if stderrs.Is(err, pgx.ErrNoRows) {
+		return errors.Wrap(err, errors.NotFound, "not found").
+			WithReason(errors.ReasonNotFound)
+	}
+
+	switch {
+	case stderrs.Is(err, context.Canceled):
+		return errors.Wrap(err, errors.Canceled, "request canceled").
+			WithReason(errors.ReasonCanceled)
+	case stderrs.Is(err, context.DeadlineExceeded):
+		return errors.Wrap(err, errors.GatewayTimeout, "database timeout").
+			WithReason(errors.ReasonGatewayTimeout)
+	}
+
 	var pgErr *pgconn.PgError
 	if stderrs.As(err, &pgErr) {
-		// common PostgreSQL error codes: https://www.postgresql.org/docs/current/errcodes-appendix.html
-		switch pgErr.Code {
-		case "23505": // unique_violation
-			return errors.New(errors.Conflict, "unique constraint violation").
-				WithReason(errors.ReasonConflict).
-				WithDetails(map[string]any{
-					"code":         pgErr.Code,
-					"constraint":   pgErr.ConstraintName,
-					"table":        pgErr.TableName,
-					"schema":       pgErr.SchemaName,
-					"column":       pgErr.ColumnName,
-					"detail":       pgErr.Detail,
-					"message":      pgErr.Message,
-					"where":        pgErr.Where,
-					"severity":     pgErr.Severity,
-					"routine":      pgErr.Routine,
-					"internal_pos": pgErr.InternalPosition,
-				})
-
-		case "23503": // foreign_key_violation
-			return errors.New(errors.Conflict, "foreign key violation").
-				WithReason(errors.ReasonConflict).
-				WithDetails(map[string]any{
-					"code":       pgErr.Code,
-					"constraint": pgErr.ConstraintName,
-					"table":      pgErr.TableName,
-					"schema":     pgErr.SchemaName,
-					"detail":     pgErr.Detail,
-					"message":    pgErr.Message,
-				})
-
-		case "23514": // check_violation
-			return errors.New(errors.BadRequest, "check constraint violation").
-				WithReason(errors.ReasonBadRequest).
-				WithDetails(map[string]any{
-					"code":       pgErr.Code,
-					"constraint": pgErr.ConstraintName,
-					"table":      pgErr.TableName,
-					"schema":     pgErr.SchemaName,
-					"detail":     pgErr.Detail,
-					"message":    pgErr.Message,
-				})
-
-		case "23502": // not_null_violation
-			return errors.New(errors.BadRequest, "null value in column violates not-null constraint").
-				WithReason(errors.ReasonBadRequest).
-				WithDetails(map[string]any{
-					"code":    pgErr.Code,
-					"table":   pgErr.TableName,
-					"schema":  pgErr.SchemaName,
-					"column":  pgErr.ColumnName,
-					"detail":  pgErr.Detail,
-					"message": pgErr.Message,
-				})
-
-		case "22P02": // invalid_text_representation
-			return errors.New(errors.BadRequest, "invalid text representation").
-				WithReason(errors.ReasonBadRequest).
-				WithDetails(map[string]any{
-					"code":    pgErr.Code,
-					"detail":  pgErr.Detail,
-					"message": pgErr.Message,
-				})
-
-		case "40001": // serialization_failure
-			return errors.New(errors.Conflict, "serialization failure").
-				WithReason(errors.ReasonConflict).
-				WithDetails(map[string]any{
-					"code":    pgErr.Code,
-					"detail":  pgErr.Detail,
-					"message": pgErr.Message,
-				})
-
-		case "40P01": // deadlock_detected
-			return errors.New(errors.Conflict, "deadlock detected").
-				WithReason(errors.ReasonConflict).
-				WithDetails(map[string]any{
-					"code":    pgErr.Code,
-					"detail":  pgErr.Detail,
-					"message": pgErr.Message,
-				})
+		tbErr := mapPgError(err, pgErr)
+		if constraint, ok := lookupConstraint(pgErr.ConstraintName); ok {
+			return constraint.toError(err, tbErr)
 		}
 
-		// default mapping for unhandled PG errors
-		return errors.New(errors.Internal, pgErr.Message).
-			WithReason(errors.ReasonInternal).
-			WithDetails(map[string]any{
+		return tbErr
+	}
+
+	if isConnectionError(err) {
+		return errors.Wrap(err, errors.Unavailable, "database unavailable").
+			WithReason(errors.ReasonUnavailable)
+	}
+
+	// non-PG error -> Internal, the driver text is kept in the internal details
+	return errors.Wrap(err, errors.Internal, "database error").
+		WithReason(errors.ReasonInternal).
+		WithInternalDetails(map[string]any{"error": err.Error()})
+}
+
+// isConnectionError - check if the error is a failure to connect or a lost connection
+func isConnectionError(err error) bool {
+	var (
+		connectErr *pgconn.ConnectError
+		opErr      *net.OpError
+	)
+
+	return stderrs.As(err, &connectErr) ||
+		stderrs.As(err, &opErr) ||
+		stderrs.Is(err, syscall.ECONNREFUSED) ||
+		stderrs.Is(err, syscall.ECONNRESET) ||
+		stderrs.Is(err, syscall.EPIPE) ||
+		stderrs.Is(err, io.ErrUnexpectedEOF) ||
+		stderrs.Is(err, net.ErrClosed)
+}
+
+// mapPgError - generic mapping of the PostgreSQL error by its SQLSTATE
+func mapPgError(err error, pgErr *pgconn.PgError) *errors.Error {
+	// common PostgreSQL error codes: https://www.postgresql.org/docs/current/errcodes-appendix.html
+	switch pgErr.Code {
+	case "23505": // unique_violation
+		return errors.Wrap(err, errors.Conflict, "unique constraint violation").
+			WithReason(errors.ReasonConflict).
+			WithInternalDetails(map[string]any{
+				"code":         pgErr.Code,
+				"constraint":   pgErr.ConstraintName,
+				"table":        pgErr.TableName,
+				"schema":       pgErr.SchemaName,
+				"column":       pgErr.ColumnName,
+				"detail":       pgErr.Detail,
+				"message":      pgErr.Message,
+				"where":        pgErr.Where,
+				"severity":     pgErr.Severity,
+				"routine":      pgErr.Routine,
+				"internal_pos": pgErr.InternalPosition,
+			})
+
+	case "23503": // foreign_key_violation
+		return errors.Wrap(err, errors.Conflict, "foreign key violation").
+			WithReason(errors.ReasonConflict).
+			WithGRPCCode(codes.FailedPrecondition).
+			WithInternalDetails(map[string]any{
+				"code":       pgErr.Code,
+				"constraint": pgErr.ConstraintName,
+				"table":      pgErr.TableName,
+				"schema":     pgErr.SchemaName,
+				"detail":     pgErr.Detail,
+				"message":    pgErr.Message,
+			})
+
+	case "23514": // check_violation
+		return errors.Wrap(err, errors.BadRequest, "check constraint violation").
+			WithReason(errors.ReasonBadRequest).
+			WithInternalDetails(map[string]any{
+				"code":       pgErr.Code,
+				"constraint": pgErr.ConstraintName,
+				"table":      pgErr.TableName,
+				"schema":     pgErr.SchemaName,
+				"detail":     pgErr.Detail,
+				"message":    pgErr.Message,
+			})
+
+	case "23502": // not_null_violation
+		return errors.Wrap(err, errors.BadRequest, "null value in column violates not-null constraint").
+			WithReason(errors.ReasonBadRequest).
+			WithInternalDetails(map[string]any{
+				"code":    pgErr.Code,
+				"table":   pgErr.TableName,
+				"schema":  pgErr.SchemaName,
+				"column":  pgErr.ColumnName,
+				"detail":  pgErr.Detail,
+				"message": pgErr.Message,
+			})
+
+	case "22P02": // invalid_text_representation
+		return errors.Wrap(err, errors.BadRequest, "invalid text representation").
+			WithReason(errors.ReasonBadRequest).
+			WithInternalDetails(map[string]any{
+				"code":    pgErr.Code,
+				"detail":  pgErr.Detail,
+				"message": pgErr.Message,
+			})
+
+	case "40001": // serialization_failure
+		return errors.Wrap(err, errors.Conflict, "serialization failure").
+			WithReason(errors.ReasonConflict).
+			WithRetryable(true).
+			WithInternalDetails(map[string]any{
+				"code":    pgErr.Code,
+				"detail":  pgErr.Detail,
+				"message": pgErr.Message,
+			})
+
+	case "40P01": // deadlock_detected
+		return errors.Wrap(err, errors.Conflict, "deadlock detected").
+			WithReason(errors.ReasonConflict).
+			WithRetryable(true).
+			WithInternalDetails(map[string]any{
+				"code":    pgErr.Code,
+				"detail":  pgErr.Detail,
+				"message": pgErr.Message,
+			})
+
+	case "22001": // string_data_right_truncation
+		return errors.Wrap(err, errors.BadRequest, "value too long").
+			WithReason(errors.ReasonBadRequest).
+			WithInternalDetails(map[string]any{
+				"code":    pgErr.Code,
+				"table":   pgErr.TableName,
+				"column":  pgErr.ColumnName,
+				"detail":  pgErr.Detail,
+				"message": pgErr.Message,
+			})
+
+	case "55P03": // lock_not_available
+		return errors.Wrap(err, errors.Aborted, "lock not available").
+			WithReason(errors.ReasonConflict).
+			WithRetryable(true).
+			WithInternalDetails(map[string]any{
+				"code":    pgErr.Code,
+				"detail":  pgErr.Detail,
+				"message": pgErr.Message,
+			})
+
+	case "57014": // query_canceled (statement_timeout or cancel request)
+		return errors.Wrap(err, errors.GatewayTimeout, "query canceled").
+			WithReason(errors.ReasonGatewayTimeout).
+			WithInternalDetails(map[string]any{
+				"code":    pgErr.Code,
+				"message": pgErr.Message,
+			})
+
+	case "53300": // too_many_connections
+		return errors.Wrap(err, errors.Unavailable, "too many connections").
+			WithReason(errors.ReasonUnavailable).
+			WithRetryable(true).
+			WithInternalDetails(map[string]any{
+				"code":    pgErr.Code,
+				"message": pgErr.Message,
+			})
+	}
+
+	// class 08 - connection exception
+	if strings.HasPrefix(pgErr.Code, "08") {
+		return errors.Wrap(err, errors.Unavailable, "database connection error").
+			WithReason(errors.ReasonUnavailable).
+			WithRetryable(true).
+			WithInternalDetails(map[string]any{
 				"code":    pgErr.Code,
 				"detail":  pgErr.Detail,
 				"message": pgErr.Message,
-				"where":   pgErr.Where,
 			})
 	}
 
-	// non-PG error -> Internal
-	return errors.New(errors.Internal, err.Error()).
-		WithReason(errors.ReasonInternal)
+	// default mapping for unhandled PG errors
+	return errors.Wrap(err, errors.Internal, "database error").
+		WithReason(errors.ReasonInternal).
+		WithInternalDetails(map[string]any{
+			"code":    pgErr.Code,
+			"detail":  pgErr.Detail,
+			"message": pgErr.Message,
+			"where":   pgErr.Where,
+		})
 }
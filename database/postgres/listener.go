@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rlapenok/toolbox/errors"
+	"github.com/rlapenok/toolbox/routine"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultListenerBackoff - default delay before the first reconnect of the listener
+	defaultListenerBackoff = 500 * time.Millisecond
+	// defaultListenerMaxBackoff - default maximum delay between reconnects of the listener
+	defaultListenerMaxBackoff = 30 * time.Second
+	// listenerCloseTimeout - timeout of closing the connection of the listener
+	listenerCloseTimeout = 5 * time.Second
+)
+
+// Notification - notification received by the listener
+type Notification = pgconn.Notification
+
+// NotificationHandler - handler of the notifications of a channel
+type NotificationHandler func(ctx context.Context, notification *Notification) error
+
+// JSONHandler - handler decoding the JSON payload of the notifications into T
+func JSONHandler[T any](fn func(ctx context.Context, payload T) error) NotificationHandler {
+	return func(ctx context.Context, notification *Notification) error {
+		var payload T
+		if err := json.Unmarshal([]byte(notification.Payload), &payload); err != nil {
+			return errors.Wrap(err, errors.BadRequest, "invalid notification payload").
+				WithReason(errors.ReasonBadRequest)
+		}
+
+		return fn(ctx, payload)
+	}
+}
+
+// Listener - LISTEN/NOTIFY subscriber on a dedicated connection of the pool.
+// The connection is taken out of the pool, after its loss the listener reconnects
+// and subscribes again with exponential backoff.
+// Notifications sent while the listener is reconnecting are lost, use OnConnect to resync.
+type Listener struct {
+	pool     *Pool
+	name     string
+	logger   *zap.Logger
+	handlers map[string][]NotificationHandler
+	channels []string
+
+	onConnect  func(ctx context.Context) error
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	started atomic.Bool
+}
+
+// NewListener - create new listener of the pool
+func NewListener(pool *Pool, name string) *Listener {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Listener{
+		pool:       pool,
+		name:       name,
+		logger:     pool.logger,
+		handlers:   map[string][]NotificationHandler{},
+		backoff:    defaultListenerBackoff,
+		maxBackoff: defaultListenerMaxBackoff,
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+}
+
+// Handle - add the handler of the channel, handlers must be added before Start
+func (l *Listener) Handle(channel string, handler NotificationHandler) *Listener {
+	if _, ok := l.handlers[channel]; !ok {
+		l.channels = append(l.channels, channel)
+	}
+	l.handlers[channel] = append(l.handlers[channel], handler)
+
+	return l
+}
+
+// OnConnect - set the function called after every (re)subscription, e.g. to invalidate a whole cache
+func (l *Listener) OnConnect(fn func(ctx context.Context) error) *Listener {
+	l.onConnect = fn
+
+	return l
+}
+
+// WithBackoff - set the delay before the first reconnect and the maximum delay between reconnects
+func (l *Listener) WithBackoff(backoff, maxBackoff time.Duration) *Listener {
+	if backoff > 0 {
+		l.backoff = backoff
+	}
+	if maxBackoff > 0 {
+		l.maxBackoff = maxBackoff
+	}
+
+	return l
+}
+
+//===============================================
+// Gracefull
+//===============================================
+
+// Name - return name of the listener
+func (l *Listener) Name() string {
+	return l.name
+}
+
+// Address - return address of the database
+func (l *Listener) Address() string {
+	config := l.pool.pool.Config().ConnConfig
+
+	return net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port)))
+}
+
+// Start - listen to the channels until Stop
+func (l *Listener) Start() error {
+	if len(l.channels) == 0 {
+		return errors.New(errors.InvalidParameter, "listener has no channels")
+	}
+
+	// already started or stopped
+	if !l.started.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer close(l.done)
+
+	return l.run()
+}
+
+// Stop - stop listening and wait for the running handler
+func (l *Listener) Stop(ctx context.Context) error {
+	l.cancel()
+
+	// the listener was never started
+	if l.started.CompareAndSwap(false, true) {
+		close(l.done)
+	}
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return MapError(ctx.Err())
+	}
+}
+
+// WithLogger - set logger to the listener
+func (l *Listener) WithLogger(logger *zap.Logger) {
+	l.logger = logger
+}
+
+// Logger - return logger of the listener
+func (l *Listener) Logger() *zap.Logger {
+	return l.logger
+}
+
+// run - listen and reconnect with backoff until the listener is stopped
+func (l *Listener) run() error {
+	for attempt := 0; ; attempt++ {
+		err := l.listen(l.ctx, func() { attempt = 0 })
+		if l.ctx.Err() != nil {
+			return nil
+		}
+
+		delay := expBackoff(l.backoff, l.maxBackoff, attempt)
+		l.logger.Error("listener is disconnected",
+			zap.String("name", l.name),
+			zap.Duration("reconnect_in", delay),
+			zap.Error(MapError(err)),
+		)
+
+		if err := sleep(l.ctx, delay); err != nil {
+			return nil
+		}
+	}
+}
+
+// listen - subscribe to the channels on a dedicated connection and dispatch notifications until an error
+func (l *Listener) listen(ctx context.Context, subscribed func()) error {
+	pooled, err := l.pool.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the connection is listening, it must not return to the pool
+	conn := pooled.Hijack()
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), listenerCloseTimeout)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	for _, channel := range l.channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+	}
+
+	subscribed()
+	l.logger.Info("listener subscribed", zap.String("name", l.name), zap.Strings("channels", l.channels))
+
+	if l.onConnect != nil {
+		if err := routine.Run(ctx, l.logger, l.onConnect); err != nil {
+			l.logger.Error("listener connect handler failed", zap.String("name", l.name), zap.Error(err))
+		}
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		l.dispatch(ctx, notification)
+	}
+}
+
+// dispatch - run the handlers of the channel, errors and panics of the handlers are logged
+func (l *Listener) dispatch(ctx context.Context, notification *Notification) {
+	for _, handler := range l.handlers[notification.Channel] {
+		err := routine.Run(ctx, l.logger, func(ctx context.Context) error {
+			return handler(ctx, notification)
+		})
+		if err != nil {
+			l.logger.Error("failed to handle notification",
+				zap.String("name", l.name),
+				zap.String("channel", notification.Channel),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// Notify - send the notification to the channel in the transaction of the context if any.
+// Notifications of a transaction are delivered after its commit.
+func (p *Pool) Notify(ctx context.Context, channel string, payload string) error {
+	if _, err := p.Querier(ctx).Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload); err != nil {
+		return MapError(err)
+	}
+
+	return nil
+}
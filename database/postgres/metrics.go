@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rlapenok/toolbox/errors"
+)
+
+// metricsNamespace - namespace of the pool metrics
+const metricsNamespace = "pgxpool"
+
+// Pool roles of the metrics labels
+const (
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+// NamedPool - pool with the name and the role of the metrics labels
+type NamedPool struct {
+	Name string
+	Role string
+	Pool *Pool
+}
+
+// statMetric - description of the metric and its value in the pool statistics
+type statMetric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     func(s *pgxpool.Stat) float64
+}
+
+// StatsCollector - prometheus collector of the pgxpool statistics labelled by the pool name and role
+type StatsCollector struct {
+	pools   []NamedPool
+	metrics []statMetric
+}
+
+// NewStatsCollector - create new collector of the pools statistics
+func NewStatsCollector(pools ...NamedPool) *StatsCollector {
+	labels := []string{"pool", "role"}
+	metric := func(name, help string, valueType prometheus.ValueType, value func(s *pgxpool.Stat) float64) statMetric {
+		return statMetric{
+			desc:      prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", name), help, labels, nil),
+			valueType: valueType,
+			value:     value,
+		}
+	}
+
+	return &StatsCollector{
+		pools: pools,
+		metrics: []statMetric{
+			metric("acquired_conns", "Number of currently acquired connections.", prometheus.GaugeValue,
+				func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) }),
+			metric("idle_conns", "Number of currently idle connections.", prometheus.GaugeValue,
+				func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) }),
+			metric("total_conns", "Total number of connections (acquired, idle and constructing).", prometheus.GaugeValue,
+				func(s *pgxpool.Stat) float64 { return float64(s.TotalConns()) }),
+			metric("constructing_conns", "Number of connections being constructed.", prometheus.GaugeValue,
+				func(s *pgxpool.Stat) float64 { return float64(s.ConstructingConns()) }),
+			metric("max_conns", "Maximum size of the pool.", prometheus.GaugeValue,
+				func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) }),
+			metric("acquire_count_total", "Cumulative count of successful acquires.", prometheus.CounterValue,
+				func(s *pgxpool.Stat) float64 { return float64(s.AcquireCount()) }),
+			metric("acquire_duration_seconds_total", "Total duration of successful acquires.", prometheus.CounterValue,
+				func(s *pgxpool.Stat) float64 { return s.AcquireDuration().Seconds() }),
+			metric("empty_acquire_count_total", "Cumulative count of successful acquires that waited for a connection.", prometheus.CounterValue,
+				func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) }),
+			metric("canceled_acquire_count_total", "Cumulative count of acquires canceled by the context.", prometheus.CounterValue,
+				func(s *pgxpool.Stat) float64 { return float64(s.CanceledAcquireCount()) }),
+			metric("new_conns_count_total", "Cumulative count of new connections opened.", prometheus.CounterValue,
+				func(s *pgxpool.Stat) float64 { return float64(s.NewConnsCount()) }),
+			metric("max_lifetime_destroy_count_total", "Cumulative count of connections destroyed because they exceeded the max lifetime.", prometheus.CounterValue,
+				func(s *pgxpool.Stat) float64 { return float64(s.MaxLifetimeDestroyCount()) }),
+			metric("max_idle_destroy_count_total", "Cumulative count of connections destroyed because they exceeded the max idle time.", prometheus.CounterValue,
+				func(s *pgxpool.Stat) float64 { return float64(s.MaxIdleDestroyCount()) }),
+		},
+	}
+}
+
+// Register - register the collector on the registry
+func (c *StatsCollector) Register(registerer prometheus.Registerer) error {
+	if err := registerer.Register(c); err != nil {
+		return errors.Wrap(err, errors.Internal, "failed to register pool metrics").
+			WithReason(errors.ReasonInternal)
+	}
+
+	return nil
+}
+
+// Describe - implements prometheus.Collector
+func (c *StatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics {
+		ch <- m.desc
+	}
+}
+
+// Collect - implements prometheus.Collector
+func (c *StatsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, p := range c.pools {
+		stat := p.Pool.pool.Stat()
+		for _, m := range c.metrics {
+			ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, m.value(stat), p.Name, p.Role)
+		}
+	}
+}
+
+// NamedPools - primary and replica pools named for the metrics: "primary", "replica_0", "replica_1", ...
+func (p *ReplicatedPool) NamedPools() []NamedPool {
+	pools := []NamedPool{{Name: RolePrimary, Role: RolePrimary, Pool: p.primary}}
+	for i, r := range p.replicas {
+		pools = append(pools, NamedPool{Name: RoleReplica + "_" + strconv.Itoa(i), Role: RoleReplica, Pool: r.pool})
+	}
+
+	return pools
+}
@@ -0,0 +1,325 @@
+package postgres
+
+import (
+	"context"
+	stderrs "errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/rlapenok/toolbox/errors"
+	"go.uber.org/zap"
+
+	pgxMigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+)
+
+// migrationLockKey - key of the advisory lock which serializes migrations of the replicas.
+// It is scoped to the database and the schema of the connection.
+const migrationLockKey = `hashtext('toolbox.migrate:' || current_database() || ':' || current_schema())`
+
+// MigrationSource - source of the migration files (<version>_<title>.up.sql / <version>_<title>.down.sql)
+type MigrationSource struct {
+	fsys fs.FS
+	path string
+}
+
+// MigrationsDir - migrations from the directory on the disk
+func MigrationsDir(path string) MigrationSource {
+	return MigrationSource{fsys: os.DirFS(path), path: "."}
+}
+
+// MigrationsFS - migrations from the directory of the file system, e.g. embed.FS shipped inside the binary
+func MigrationsFS(fsys fs.FS, path string) MigrationSource {
+	return MigrationSource{fsys: fsys, path: path}
+}
+
+// MigrationStatus - state of the migrations of the database
+type MigrationStatus struct {
+	// Version - current version, 0 when no migration is applied
+	Version uint
+	// Applied - at least one migration is applied
+	Applied bool
+	// Dirty - the last migration failed and the version must be forced
+	Dirty bool
+	// Pending - versions of the source which are not applied yet
+	Pending []uint
+}
+
+// Migrate - apply all up migrations from the directory
+func (p *Pool) Migrate(ctx context.Context, migrationsPath string) error {
+	return p.MigrateUp(ctx, MigrationsDir(migrationsPath))
+}
+
+// MigrateUp - apply all up migrations
+func (p *Pool) MigrateUp(ctx context.Context, src MigrationSource) error {
+	return p.migrate(ctx, src, func(m *migrate.Migrate) error {
+		return m.Up()
+	})
+}
+
+// MigrateDown - roll back the given number of migrations, all of them when steps <= 0
+func (p *Pool) MigrateDown(ctx context.Context, src MigrationSource, steps int) error {
+	return p.migrate(ctx, src, func(m *migrate.Migrate) error {
+		if steps <= 0 {
+			return m.Down()
+		}
+
+		return m.Steps(-steps)
+	})
+}
+
+// MigrateTo - migrate up or down to the given version of the source
+func (p *Pool) MigrateTo(ctx context.Context, src MigrationSource, version uint) error {
+	return p.migrate(ctx, src, func(m *migrate.Migrate) error {
+		return m.Migrate(version)
+	})
+}
+
+// ForceMigration - set the version without running migrations and reset the dirty flag.
+// Version -1 means that no migration is applied.
+func (p *Pool) ForceMigration(ctx context.Context, src MigrationSource, version int) error {
+	return p.migrate(ctx, src, func(m *migrate.Migrate) error {
+		return m.Force(version)
+	})
+}
+
+// MigrationStatus - get the current version, the dirty flag and the pending migrations
+func (p *Pool) MigrationStatus(ctx context.Context, src MigrationSource) (*MigrationStatus, error) {
+	status := &MigrationStatus{}
+
+	err := p.withMigrate(ctx, src, func(m *migrate.Migrate, sourceDrv source.Driver) error {
+		version, dirty, err := m.Version()
+		switch {
+		case stderrs.Is(err, migrate.ErrNilVersion):
+		case err != nil:
+			return err
+		default:
+			status.Version, status.Dirty, status.Applied = version, dirty, true
+		}
+
+		pending, err := pendingVersions(sourceDrv, status)
+		if err != nil {
+			return err
+		}
+		status.Pending = pending
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// migrate - run the migration under the advisory lock.
+// The lock is a session lock, so it requires a session pooling mode of pgbouncer.
+func (p *Pool) migrate(ctx context.Context, src MigrationSource, fn func(m *migrate.Migrate) error) error {
+	unlock, err := p.lockMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return p.withMigrate(ctx, src, func(m *migrate.Migrate, _ source.Driver) error {
+		// stop between migrations when the context is done
+		done := make(chan struct{})
+		signalled := make(chan bool, 1)
+		go func() {
+			select {
+			case <-ctx.Done():
+				m.GracefulStop <- true
+				signalled <- true
+			case <-done:
+				signalled <- false
+			}
+		}()
+
+		err := fn(m)
+		close(done)
+
+		// a stopped run returns nil, migrate takes the signal from the buffered channel
+		// only when it stops, a signal left in the channel came after the run was complete
+		interrupted := <-signalled && len(m.GracefulStop) == 0
+
+		if stderrs.Is(err, migrate.ErrNoChange) {
+			p.logger.Info("migrations: no change")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if interrupted {
+			return errors.Wrap(ctx.Err(), errors.Canceled, "migrations stopped").
+				WithReason(errors.ReasonCanceled)
+		}
+
+		version, dirty, err := m.Version()
+		switch {
+		case stderrs.Is(err, migrate.ErrNilVersion):
+			p.logger.Info("migrations: done, no version")
+		case err != nil:
+			return err
+		default:
+			p.logger.Info("migrations: done", zap.Uint("version", version), zap.Bool("dirty", dirty))
+		}
+
+		return nil
+	})
+}
+
+// withMigrate - create the migrate instance on a dedicated connection and close it after use
+func (p *Pool) withMigrate(ctx context.Context, src MigrationSource, fn func(m *migrate.Migrate, sourceDrv source.Driver) error) error {
+	if err := ctx.Err(); err != nil {
+		return MapError(err)
+	}
+
+	sourceDrv, err := iofs.New(src.fsys, src.path)
+	if err != nil {
+		return errors.Wrap(err, errors.InvalidParameter, "failed to open migrations source").
+			WithReason(errors.ReasonBadRequest)
+	}
+
+	db := stdlib.OpenDB(*p.pool.Config().ConnConfig)
+	// driver.Close closes db too, closing it twice is a no-op
+	defer db.Close()
+
+	driver, err := pgxMigrate.WithInstance(db, &pgxMigrate.Config{})
+	if err != nil {
+		_ = sourceDrv.Close()
+		return migrationError(err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", sourceDrv, "pgx", driver)
+	if err != nil {
+		_ = sourceDrv.Close()
+		_ = driver.Close()
+		return migrationError(err)
+	}
+	m.Log = migrateLogger{logger: p.logger}
+
+	err = fn(m, sourceDrv)
+
+	if srcErr, dbErr := m.Close(); err == nil {
+		if srcErr != nil {
+			err = srcErr
+		} else {
+			err = dbErr
+		}
+	}
+
+	return migrationError(err)
+}
+
+// lockMigrations - wait for the advisory lock of the migrations until the context is done
+func (p *Pool) lockMigrations(ctx context.Context) (func(), error) {
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, MapError(err)
+	}
+
+	p.logger.Info("migrations: waiting for lock")
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock("+migrationLockKey+")"); err != nil {
+		conn.Release()
+		return nil, MapError(err)
+	}
+	p.logger.Info("migrations: lock acquired")
+
+	return func() {
+		unlockMigrations(context.WithoutCancel(ctx), conn, p.logger)
+	}, nil
+}
+
+// unlockMigrations - release the advisory lock, the connection is closed if the lock can't be released
+func unlockMigrations(ctx context.Context, conn *pgxpool.Conn, logger *zap.Logger) {
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_unlock("+migrationLockKey+")"); err != nil {
+		logger.Error("migrations: failed to release lock", zap.Error(err))
+		// the session lock is released together with the session
+		_ = conn.Conn().Close(ctx)
+	}
+}
+
+// pendingVersions - versions of the source after the current version
+func pendingVersions(sourceDrv source.Driver, status *MigrationStatus) ([]uint, error) {
+	var pending []uint
+
+	version, err := sourceDrv.First()
+	for err == nil {
+		if !status.Applied || version > status.Version {
+			pending = append(pending, version)
+		}
+		version, err = sourceDrv.Next(version)
+	}
+	if !stderrs.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// migrationError - convert errors of golang-migrate into toolbox errors
+func migrationError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var (
+		dirtyErr migrate.ErrDirty
+		limitErr migrate.ErrShortLimit
+		dbErr    database.Error
+	)
+	switch {
+	case stderrs.As(err, &dirtyErr):
+		return errors.Wrap(err, errors.FailedPrecondition,
+			fmt.Sprintf("database is dirty at version %d, force the version to recover", dirtyErr.Version)).
+			WithReason(errors.ReasonFailedPrecondition)
+	case stderrs.As(err, &limitErr):
+		return errors.Wrap(err, errors.OutOfRange,
+			fmt.Sprintf("not enough migrations, %d steps short", limitErr.Short)).
+			WithReason(errors.ReasonOutOfRange)
+	case stderrs.Is(err, os.ErrNotExist):
+		return errors.Wrap(err, errors.NotFound, "migration version not found").
+			WithReason(errors.ReasonNotFound)
+	case stderrs.Is(err, migrate.ErrInvalidVersion):
+		return errors.Wrap(err, errors.InvalidParameter, "invalid migration version").
+			WithReason(errors.ReasonBadRequest)
+	case stderrs.As(err, &dbErr) && dbErr.OrigErr != nil:
+		// database.Error doesn't unwrap, map the original error of the query
+		tbErr := MapError(dbErr.OrigErr)
+		internal, _ := tbErr.InternalDetails().(map[string]any)
+		if internal == nil {
+			internal = map[string]any{}
+		}
+		internal["line"] = dbErr.Line
+		internal["query"] = string(dbErr.Query)
+
+		return tbErr.WithInternalDetails(internal)
+	}
+
+	return MapError(err)
+}
+
+// migrateLogger - zap adapter of the golang-migrate logger
+type migrateLogger struct {
+	logger *zap.Logger
+}
+
+// Printf - log the progress of the migrations
+func (l migrateLogger) Printf(format string, v ...any) {
+	l.logger.Info("migrations: " + strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+// Verbose - verbose output is enabled with the debug level
+func (l migrateLogger) Verbose() bool {
+	return l.logger.Core().Enabled(zap.DebugLevel)
+}
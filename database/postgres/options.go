@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultReplicaCheckPeriod - default period of the replica health and lag checks
+	defaultReplicaCheckPeriod = 5 * time.Second
+)
+
+// options - settings of the pool
+type options struct {
+	logger      *zap.Logger
+	queryLogger []QueryLoggerOption
+
+	// settings of ReplicatedPool
+	balancing          Balancing
+	maxReplicationLag  time.Duration
+	replicaCheckPeriod time.Duration
+}
+
+// Option - option of the pool
+type Option func(*options)
+
+// WithLogger - set the logger of the pool (migration progress, etc.)
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithQueryLogger - log queries, batches, copies and connects through the logger of the pool, see QueryLogger
+func WithQueryLogger(opts ...QueryLoggerOption) Option {
+	return func(o *options) {
+		o.queryLogger = append([]QueryLoggerOption{}, opts...)
+	}
+}
+
+// WithBalancing - set the replica selection strategy of ReplicatedPool (round robin by default)
+func WithBalancing(balancing Balancing) Option {
+	return func(o *options) {
+		o.balancing = balancing
+	}
+}
+
+// WithMaxReplicationLag - exclude replicas of ReplicatedPool lagging behind the primary more than lag.
+// Zero disables the lag check.
+func WithMaxReplicationLag(lag time.Duration) Option {
+	return func(o *options) {
+		o.maxReplicationLag = lag
+	}
+}
+
+// WithReplicaCheckPeriod - set the period of the replica health and lag checks of ReplicatedPool
+func WithReplicaCheckPeriod(period time.Duration) Option {
+	return func(o *options) {
+		o.replicaCheckPeriod = period
+	}
+}
+
+// newOptions - apply options over the defaults
+func newOptions(opts []Option) *options {
+	o := &options{
+		logger:             zap.NewNop(),
+		balancing:          BalancingRoundRobin,
+		replicaCheckPeriod: defaultReplicaCheckPeriod,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.logger == nil {
+		o.logger = zap.NewNop()
+	}
+	if o.replicaCheckPeriod <= 0 {
+		o.replicaCheckPeriod = defaultReplicaCheckPeriod
+	}
+
+	return o
+}
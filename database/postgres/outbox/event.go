@@ -0,0 +1,136 @@
+// Package outbox - transactional outbox: events are inserted in the transaction of the business data
+// and delivered to a sink by the relay after the commit
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rlapenok/toolbox/database/postgres"
+	"github.com/rlapenok/toolbox/errors"
+)
+
+// DefaultTable - default outbox table
+const DefaultTable = "outbox"
+
+// Event - event of the outbox
+type Event struct {
+	// ID - id of the event assigned by Publish, use it as an idempotency key
+	ID int64 `json:"id"`
+	// AggregateKey - key of the aggregate, events of the same key are delivered in the publication order
+	AggregateKey string `json:"aggregate_key"`
+	// Type - type of the event
+	Type string `json:"type"`
+	// Payload - JSON payload of the event
+	Payload json.RawMessage `json:"payload"`
+	// Headers - headers of the event (trace ids, etc.)
+	Headers map[string]string `json:"headers,omitempty"`
+	// CreatedAt - time of the publication
+	CreatedAt time.Time `json:"created_at"`
+	// Attempts - number of failed delivery attempts
+	Attempts int `json:"attempts"`
+}
+
+// NewEvent - create new event with the payload encoded as JSON
+func NewEvent(aggregateKey string, eventType string, payload any) (*Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.InvalidParameter, "failed to encode event payload").
+			WithReason(errors.ReasonBadRequest)
+	}
+
+	return &Event{
+		AggregateKey: aggregateKey,
+		Type:         eventType,
+		Payload:      data,
+	}, nil
+}
+
+// WithHeader - add the header to the event
+func (e *Event) WithHeader(key string, value string) *Event {
+	if e.Headers == nil {
+		e.Headers = map[string]string{}
+	}
+	e.Headers[key] = value
+
+	return e
+}
+
+// Publish - insert the event into the default outbox table in the transaction
+func Publish(ctx context.Context, tx pgx.Tx, event *Event) error {
+	return PublishTo(ctx, tx, DefaultTable, event)
+}
+
+// PublishTo - insert the event into the outbox table in the transaction, the id and the time are set to the event.
+// Publishers of the same aggregate key are serialized until the commit, so the ids of a key
+// grow in the commit order and the relay never sees a later event before an earlier one.
+func PublishTo(ctx context.Context, tx pgx.Tx, table string, event *Event) error {
+	if event.AggregateKey == "" || event.Type == "" {
+		return errors.New(errors.InvalidParameter, "event aggregate key and type are required").
+			WithReason(errors.ReasonBadRequest)
+	}
+
+	headers := "{}"
+	if event.Headers != nil {
+		data, err := json.Marshal(event.Headers)
+		if err != nil {
+			return errors.Wrap(err, errors.InvalidParameter, "failed to encode event headers").
+				WithReason(errors.ReasonBadRequest)
+		}
+		headers = string(data)
+	}
+
+	payload := "null"
+	if event.Payload != nil {
+		payload = string(event.Payload)
+	}
+
+	// the transaction-level lock of the key in the namespace of the table
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))", table, event.AggregateKey); err != nil {
+		return postgres.MapError(err)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (aggregate_key, event_type, payload, headers)
+VALUES ($1, $2, $3::jsonb, $4::jsonb)
+RETURNING id, created_at`, tableIdentifier(table))
+
+	if err := tx.QueryRow(ctx, query, event.AggregateKey, event.Type, payload, headers).Scan(&event.ID, &event.CreatedAt); err != nil {
+		return postgres.MapError(err)
+	}
+
+	return nil
+}
+
+// Schema - DDL of the outbox table, add it to the migrations of the service.
+// Delivered events are deleted, dead-lettered events stay with the status "dead".
+func Schema(table string) string {
+	name := tableIdentifier(table)
+	index := pgx.Identifier{strings.ReplaceAll(table, ".", "_") + "_pending_idx"}.Sanitize()
+
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+	id              BIGSERIAL PRIMARY KEY,
+	aggregate_key   TEXT        NOT NULL,
+	event_type      TEXT        NOT NULL,
+	payload         JSONB       NOT NULL,
+	headers         JSONB       NOT NULL DEFAULT '{}',
+	created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+	status          TEXT        NOT NULL DEFAULT 'pending',
+	attempts        INT         NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	locked_until    TIMESTAMPTZ,
+	locked_by       UUID,
+	last_error      TEXT
+);
+
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (aggregate_key, id) WHERE status = 'pending';
+`, name, index)
+}
+
+// tableIdentifier - sanitized identifier of the table, "schema.table" is supported
+func tableIdentifier(table string) string {
+	return pgx.Identifier(strings.Split(table, ".")).Sanitize()
+}
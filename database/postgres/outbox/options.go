@@ -0,0 +1,161 @@
+package outbox
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultBatchSize - default number of events fetched by one poll
+	defaultBatchSize = 100
+	// defaultPollInterval - default delay between polls of an empty outbox
+	defaultPollInterval = time.Second
+	// defaultMaxAttempts - default number of delivery attempts before dead-lettering
+	defaultMaxAttempts = 10
+	// defaultBackoff - default delay before the first retry of a delivery
+	defaultBackoff = time.Second
+	// defaultMaxBackoff - default maximum delay between retries of a delivery
+	defaultMaxBackoff = 10 * time.Minute
+	// defaultSendTimeout - default timeout of sending one event to the sink
+	defaultSendTimeout = 30 * time.Second
+	// defaultLease - default time the claimed events are reserved for the relay
+	defaultLease = 5 * time.Minute
+)
+
+// options - settings of the relay
+type options struct {
+	name         string
+	table        string
+	logger       *zap.Logger
+	batchSize    int
+	pollInterval time.Duration
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	sendTimeout  time.Duration
+	lease        time.Duration
+	deadLetter   Sink
+}
+
+// Option - option of the relay
+type Option func(*options)
+
+// WithName - set the name of the relay
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithTable - set the outbox table
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// WithLogger - set the logger of the relay
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithBatchSize - set the number of events fetched by one poll
+func WithBatchSize(size int) Option {
+	return func(o *options) {
+		o.batchSize = size
+	}
+}
+
+// WithPollInterval - set the delay between polls when the outbox has no ready events
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = interval
+	}
+}
+
+// WithMaxAttempts - set the number of delivery attempts before the event is dead-lettered
+func WithMaxAttempts(attempts int) Option {
+	return func(o *options) {
+		o.maxAttempts = attempts
+	}
+}
+
+// WithBackoff - set the delay before the first retry and the maximum delay between retries
+func WithBackoff(backoff, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.backoff = backoff
+		o.maxBackoff = maxBackoff
+	}
+}
+
+// WithSendTimeout - set the timeout of sending one event to the sink, a timed out send is retried
+func WithSendTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.sendTimeout = timeout
+	}
+}
+
+// WithLease - set the time the claimed events are reserved for the relay.
+// Events not sent in time are released and claimed by the next batch, the lease is at least twice the send timeout.
+func WithLease(lease time.Duration) Option {
+	return func(o *options) {
+		o.lease = lease
+	}
+}
+
+// WithDeadLetterSink - send dead-lettered events to the sink in addition to marking them as dead
+func WithDeadLetterSink(sink Sink) Option {
+	return func(o *options) {
+		o.deadLetter = sink
+	}
+}
+
+// newOptions - apply options over the defaults
+func newOptions(opts []Option) *options {
+	o := &options{
+		name:         "outbox-relay",
+		table:        DefaultTable,
+		logger:       zap.NewNop(),
+		batchSize:    defaultBatchSize,
+		pollInterval: defaultPollInterval,
+		maxAttempts:  defaultMaxAttempts,
+		backoff:      defaultBackoff,
+		maxBackoff:   defaultMaxBackoff,
+		sendTimeout:  defaultSendTimeout,
+		lease:        defaultLease,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.logger == nil {
+		o.logger = zap.NewNop()
+	}
+	if o.batchSize <= 0 {
+		o.batchSize = defaultBatchSize
+	}
+	if o.pollInterval <= 0 {
+		o.pollInterval = defaultPollInterval
+	}
+	if o.maxAttempts <= 0 {
+		o.maxAttempts = defaultMaxAttempts
+	}
+	if o.backoff <= 0 {
+		o.backoff = defaultBackoff
+	}
+	if o.maxBackoff <= 0 {
+		o.maxBackoff = defaultMaxBackoff
+	}
+	if o.sendTimeout <= 0 {
+		o.sendTimeout = defaultSendTimeout
+	}
+	if o.lease < 2*o.sendTimeout {
+		o.lease = max(defaultLease, 2*o.sendTimeout)
+	}
+
+	return o
+}
@@ -0,0 +1,353 @@
+package outbox
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rlapenok/toolbox/database/postgres"
+	"github.com/rlapenok/toolbox/errors"
+	"github.com/rlapenok/toolbox/routine"
+	"go.uber.org/zap"
+)
+
+// finishTimeout - time given to the canceled batch to finish when Stop runs out of time
+// and timeout of recording the result of an event
+const finishTimeout = 5 * time.Second
+
+// Relay - worker delivering the events of the outbox to the sink.
+//
+// Only the oldest pending event of every aggregate key is claimed and publishers of a key are
+// serialized by PublishTo, so events of the same key are delivered in the publication order, while different keys are delivered concurrently by
+// several relays. Claimed events are leased (locked_until) in a short transaction and sent
+// outside of it, the result of every event is committed on its own. A failed event is retried with exponential
+// backoff (or the Retry-After of the sink error) and blocks the later events of its key until
+// it's delivered or dead-lettered after the maximum number of attempts.
+// Delivery is at least once: a crash after the send or an expired lease repeats it.
+type Relay struct {
+	pool *postgres.Pool
+	sink Sink
+	o    *options
+
+	queries relayQueries
+
+	// ctx - context of the polling, canceled by Stop
+	ctx    context.Context
+	cancel context.CancelFunc
+	// batchCtx - context of the current batch, canceled when Stop runs out of time
+	batchCtx    context.Context
+	batchCancel context.CancelFunc
+
+	done    chan struct{}
+	started atomic.Bool
+}
+
+// relayQueries - queries of the relay for the configured table
+type relayQueries struct {
+	claim      string
+	delete     string
+	retry      string
+	deadLetter string
+	release    string
+}
+
+// NewRelay - create new relay of the outbox of the pool
+func NewRelay(pool *postgres.Pool, sink Sink, opts ...Option) *Relay {
+	o := newOptions(opts)
+	table := tableIdentifier(o.table)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	batchCtx, batchCancel := context.WithCancel(context.Background())
+
+	return &Relay{
+		pool: pool,
+		sink: sink,
+		o:    o,
+		queries: relayQueries{
+			claim: fmt.Sprintf(`UPDATE %[1]s
+SET locked_until = now() + make_interval(secs => $2), locked_by = $3::uuid
+WHERE id IN (
+	SELECT o.id FROM %[1]s o
+	WHERE o.status = 'pending'
+		AND o.next_attempt_at <= now()
+		AND (o.locked_until IS NULL OR o.locked_until < now())
+		AND NOT EXISTS (
+			SELECT 1 FROM %[1]s p
+			WHERE p.aggregate_key = o.aggregate_key AND p.status = 'pending' AND p.id < o.id
+		)
+	ORDER BY o.id
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING id, aggregate_key, event_type, payload, headers, created_at, attempts`, table),
+			delete: fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND locked_by = $2::uuid`, table),
+			retry: fmt.Sprintf(`UPDATE %s
+SET attempts = attempts + 1, next_attempt_at = now() + make_interval(secs => $3), last_error = $4,
+	locked_until = NULL, locked_by = NULL
+WHERE id = $1 AND locked_by = $2::uuid`, table),
+			deadLetter: fmt.Sprintf(`UPDATE %s
+SET attempts = attempts + 1, status = 'dead', last_error = $3, locked_until = NULL, locked_by = NULL
+WHERE id = $1 AND locked_by = $2::uuid`, table),
+			release: fmt.Sprintf(`UPDATE %s
+SET locked_until = NULL, locked_by = NULL
+WHERE id = $1 AND locked_by = $2::uuid`, table),
+		},
+		ctx:         ctx,
+		cancel:      cancel,
+		batchCtx:    batchCtx,
+		batchCancel: batchCancel,
+		done:        make(chan struct{}),
+	}
+}
+
+//===============================================
+// Gracefull
+//===============================================
+
+// Name - return name of the relay
+func (r *Relay) Name() string {
+	return r.o.name
+}
+
+// Address - return address of the database
+func (r *Relay) Address() string {
+	config := r.pool.Pgx().Config().ConnConfig
+
+	return net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port)))
+}
+
+// Start - deliver the events until Stop
+func (r *Relay) Start() error {
+	// already started or stopped
+	if !r.started.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer close(r.done)
+
+	r.run()
+
+	return nil
+}
+
+// Stop - stop polling and wait for the current batch to be delivered.
+// When ctx is done first, the sends of the batch are canceled.
+func (r *Relay) Stop(ctx context.Context) error {
+	r.cancel()
+
+	// the relay was never started
+	if r.started.CompareAndSwap(false, true) {
+		close(r.done)
+	}
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	r.batchCancel()
+
+	select {
+	case <-r.done:
+	case <-time.After(finishTimeout):
+	}
+
+	return postgres.MapError(ctx.Err())
+}
+
+// WithLogger - set logger to the relay
+func (r *Relay) WithLogger(logger *zap.Logger) {
+	r.o.logger = logger
+}
+
+// Logger - return logger of the relay
+func (r *Relay) Logger() *zap.Logger {
+	return r.o.logger
+}
+
+// run - poll the outbox until the relay is stopped, a full batch is followed by the next one immediately
+func (r *Relay) run() {
+	for {
+		// the started batch is finished even if the relay is stopped, unless Stop runs out of time
+		var processed int
+		err := routine.Run(r.batchCtx, r.o.logger, func(ctx context.Context) error {
+			var err error
+			processed, err = r.relayBatch(ctx)
+			return err
+		})
+		if err != nil {
+			r.o.logger.Error("failed to relay outbox events", zap.String("name", r.o.name), zap.Error(err))
+		}
+
+		if r.ctx.Err() != nil {
+			return
+		}
+		if err == nil && processed == r.o.batchSize {
+			continue
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(r.o.pollInterval):
+		}
+	}
+}
+
+// relayBatch - lease ready events, send them to the sink and record the result of every event on its own.
+// Events left when the lease is about to expire or the batch is canceled are released.
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	token := uuid.NewString()
+	deadline := time.Now().Add(r.o.lease)
+
+	events, err := r.claim(ctx, token)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, event := range events {
+		if ctx.Err() != nil || time.Now().Add(r.o.sendTimeout).After(deadline) {
+			r.release(events[i:], token)
+			break
+		}
+
+		r.deliver(ctx, event, token)
+	}
+
+	return len(events), nil
+}
+
+// claim - lease the oldest ready event of every aggregate key, the lease is committed before the sends
+func (r *Relay) claim(ctx context.Context, token string) ([]*Event, error) {
+	rows, err := r.pool.Pgx().Query(ctx, r.queries.claim, r.o.batchSize, r.o.lease.Seconds(), token)
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		event := &Event{}
+		if err := rows.Scan(&event.ID, &event.AggregateKey, &event.Type, &event.Payload,
+			&event.Headers, &event.CreatedAt, &event.Attempts); err != nil {
+			return nil, postgres.MapError(err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	slices.SortFunc(events, func(a, b *Event) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return events, nil
+}
+
+// deliver - send the event and delete it, schedule a retry or dead-letter it on failure
+func (r *Relay) deliver(ctx context.Context, event *Event, token string) {
+	sendErr := r.send(ctx, r.sink, event)
+	if sendErr == nil {
+		r.finish(event, r.queries.delete, event.ID, token)
+		return
+	}
+
+	// the send was interrupted by Stop, the attempt is not counted
+	if ctx.Err() != nil {
+		r.release([]*Event{event}, token)
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("name", r.o.name),
+		zap.Int64("id", event.ID),
+		zap.String("aggregate_key", event.AggregateKey),
+		zap.String("type", event.Type),
+		zap.Int("attempt", event.Attempts+1),
+		zap.Error(sendErr),
+	}
+
+	if event.Attempts+1 >= r.o.maxAttempts {
+		r.o.logger.Error("outbox event dead-lettered", fields...)
+		r.sendDeadLetter(ctx, event)
+		r.finish(event, r.queries.deadLetter, event.ID, token, sendErr.Error())
+		return
+	}
+
+	delay := r.retryDelay(event.Attempts, sendErr)
+	r.o.logger.Warn("failed to deliver outbox event", append(fields, zap.Duration("retry_in", delay))...)
+	r.finish(event, r.queries.retry, event.ID, token, delay.Seconds(), sendErr.Error())
+}
+
+// release - return the leased events to the outbox without counting an attempt
+func (r *Relay) release(events []*Event, token string) {
+	for _, event := range events {
+		r.finish(event, r.queries.release, event.ID, token)
+	}
+}
+
+// finish - record the result of the event, a lost lease means the event was claimed again and is skipped
+func (r *Relay) finish(event *Event, query string, args ...any) {
+	ctx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer cancel()
+
+	tag, err := r.pool.Pgx().Exec(ctx, query, args...)
+	if err != nil {
+		r.o.logger.Error("failed to record outbox event result",
+			zap.String("name", r.o.name),
+			zap.Int64("id", event.ID),
+			zap.Error(postgres.MapError(err)),
+		)
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		r.o.logger.Warn("outbox event lease lost, the result is skipped",
+			zap.String("name", r.o.name),
+			zap.Int64("id", event.ID),
+		)
+	}
+}
+
+// sendDeadLetter - send the dead-lettered event to the dead letter sink if any
+func (r *Relay) sendDeadLetter(ctx context.Context, event *Event) {
+	if r.o.deadLetter == nil {
+		return
+	}
+
+	if err := r.send(ctx, r.o.deadLetter, event); err != nil {
+		r.o.logger.Error("failed to send outbox event to the dead letter sink",
+			zap.String("name", r.o.name),
+			zap.Int64("id", event.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// send - send the event to the sink within the send timeout
+func (r *Relay) send(ctx context.Context, sink Sink, event *Event) error {
+	ctx, cancel := context.WithTimeout(ctx, r.o.sendTimeout)
+	defer cancel()
+
+	return routine.Run(ctx, r.o.logger, func(ctx context.Context) error {
+		return sink.Send(ctx, event)
+	})
+}
+
+// retryDelay - exponential backoff of the attempt, at least the Retry-After of the sink error
+func (r *Relay) retryDelay(attempts int, err error) time.Duration {
+	delay := postgres.Backoff(r.o.backoff, r.o.maxBackoff, attempts)
+
+	if tbErr, ok := errors.From(err); ok && tbErr.RetryAfter() > delay {
+		delay = tbErr.RetryAfter()
+	}
+
+	return delay
+}
@@ -0,0 +1,172 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/rlapenok/toolbox/errors"
+	"go.uber.org/zap"
+)
+
+// Sink - destination of the events delivered by the relay.
+// An event may be delivered more than once, sinks must be idempotent by the event id.
+type Sink interface {
+	Send(ctx context.Context, event *Event) error
+}
+
+// SinkFunc - function implementing Sink
+type SinkFunc func(ctx context.Context, event *Event) error
+
+// Send - implements Sink
+func (f SinkFunc) Send(ctx context.Context, event *Event) error {
+	return f(ctx, event)
+}
+
+// LogSink - sink logging the events
+type LogSink struct {
+	logger *zap.Logger
+}
+
+// NewLogSink - create new sink logging the events with the info level
+func NewLogSink(logger *zap.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+// Send - implements Sink
+func (s *LogSink) Send(_ context.Context, event *Event) error {
+	s.logger.Info("outbox event",
+		zap.Int64("id", event.ID),
+		zap.String("aggregate_key", event.AggregateKey),
+		zap.String("type", event.Type),
+		zap.ByteString("payload", event.Payload),
+		zap.Any("headers", event.Headers),
+	)
+
+	return nil
+}
+
+// MemorySink - sink keeping the events in memory, for tests
+type MemorySink struct {
+	mu     sync.Mutex
+	events []*Event
+	err    error
+}
+
+// NewMemorySink - create new in-memory sink
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+// Send - implements Sink
+func (s *MemorySink) Send(_ context.Context, event *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	s.events = append(s.events, event)
+
+	return nil
+}
+
+// SetError - fail the next sends with err, nil restores the delivery
+func (s *MemorySink) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+}
+
+// Events - get the delivered events in the delivery order
+func (s *MemorySink) Events() []*Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*Event(nil), s.events...)
+}
+
+// Reset - forget the delivered events
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = nil
+}
+
+// HTTPSink - sink posting the events as JSON to a webhook
+type HTTPSink struct {
+	url     string
+	client  *http.Client
+	headers http.Header
+}
+
+// NewHTTPSink - create new webhook sink, http.DefaultClient is used if client is nil.
+// Responses with a status of 400 and above are failures, their toolbox or problem+json
+// body and Retry-After header are decoded.
+func NewHTTPSink(url string, client *http.Client) *HTTPSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &HTTPSink{
+		url:     url,
+		client:  client,
+		headers: http.Header{},
+	}
+}
+
+// WithHeader - add the header to every request (authorization, etc.)
+func (s *HTTPSink) WithHeader(key string, value string) *HTTPSink {
+	s.headers.Add(key, value)
+
+	return s
+}
+
+// Send - implements Sink
+func (s *HTTPSink) Send(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, errors.Internal, "failed to encode event").
+			WithReason(errors.ReasonInternal)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, errors.InvalidParameter, "failed to create webhook request").
+			WithReason(errors.ReasonBadRequest)
+	}
+
+	for key, values := range s.headers {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	for key, value := range event.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", strconv.FormatInt(event.ID, 10))
+	req.Header.Set("X-Event-Type", event.Type)
+	req.Header.Set("X-Aggregate-Key", event.AggregateKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, errors.Unavailable, "failed to send webhook").
+			WithReason(errors.ReasonUnavailable)
+	}
+	defer resp.Body.Close()
+
+	if tbErr := errors.FromHTTPResponse(resp); tbErr != nil {
+		return tbErr
+	}
+
+	// drain the body to reuse the connection
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return nil
+}
@@ -2,23 +2,26 @@ package postgres
 
 import (
 	"context"
+	"net"
+	"time"
 
-	"github.com/golang-migrate/migrate/v4"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/rlapenok/toolbox/database"
-
-	pgxMigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/rlapenok/toolbox/errors"
+	"go.uber.org/zap"
 )
 
 // Pool - connection pool to PostgreSQL
 type Pool struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	logger *zap.Logger
 }
 
 // NewPool - create new pool
-func NewPool(ctx context.Context, config PoolConfig) (*Pool, error) {
+func NewPool(ctx context.Context, config PoolConfig, opts ...Option) (*Pool, error) {
+	o := newOptions(opts)
 
 	// Parse config
 	poolConfig, err := pgxpool.ParseConfig("")
@@ -41,13 +44,44 @@ func NewPool(ctx context.Context, config PoolConfig) (*Pool, error) {
 		conn.RuntimeParams["search_path"] = config.GetSchema()
 	}
 
-	// Set TLS config
-	if config.GetSSLMode() != "disable" && config.GetSSLCert() != "" && config.GetSSLKey() != "" {
-		tlsCfg, err := database.BuildTLSConfig(config.GetSSLRoot(), config.GetSSLCert(), config.GetSSLKey())
-		if err != nil {
-			return nil, MapError(err)
+	// Set TLS config, the configs after the first one are fallbacks (sslmode allow and prefer)
+	tlsConfigs, err := database.BuildTLSConfigs(tlsOptions(config))
+	if err != nil {
+		return nil, MapError(err)
+	}
+	conn.TLSConfig = tlsConfigs[0]
+	conn.Fallbacks = nil
+	for _, tlsConfig := range tlsConfigs[1:] {
+		conn.Fallbacks = append(conn.Fallbacks, &pgconn.FallbackConfig{
+			Host:      conn.Host,
+			Port:      conn.Port,
+			TLSConfig: tlsConfig,
+		})
+	}
+
+	// Set application name
+	if c, ok := config.(ApplicationNameConfig); ok && c.GetApplicationName() != "" {
+		conn.RuntimeParams["application_name"] = c.GetApplicationName()
+	}
+
+	// Set connect timeout
+	if c, ok := config.(ConnectTimeoutConfig); ok && c.GetConnectTimeout() > 0 {
+		conn.ConnectTimeout = c.GetConnectTimeout()
+	}
+
+	// Set TCP keepalive and connect timeout through a custom dialer
+	conn.DialFunc = newDialer(config, conn.ConnectTimeout).DialContext
+
+	// Set query exec mode
+	if c, ok := config.(QueryExecModeConfig); ok && c.GetQueryExecMode() != "" {
+		if err := applyQueryExecMode(poolConfig.ConnConfig, c.GetQueryExecMode()); err != nil {
+			return nil, err
 		}
-		conn.TLSConfig = tlsCfg
+	}
+
+	// Set query logger
+	if o.queryLogger != nil {
+		poolConfig.ConnConfig.Tracer = NewQueryLogger(o.logger, o.queryLogger...)
 	}
 
 	// Set pool config
@@ -56,38 +90,73 @@ func NewPool(ctx context.Context, config PoolConfig) (*Pool, error) {
 	poolConfig.MaxConnLifetime = config.GetMaxConnLifetime()
 	poolConfig.MaxConnIdleTime = config.GetMaxConnIdleTime()
 
+	// Set health check period
+	if c, ok := config.(HealthCheckConfig); ok && c.GetHealthCheckPeriod() > 0 {
+		poolConfig.HealthCheckPeriod = c.GetHealthCheckPeriod()
+	}
+
 	// Create pool
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, MapError(err)
 	}
 
-	return &Pool{pool: pool}, nil
+	return &Pool{pool: pool, logger: o.logger}, nil
 }
 
-func (p *Pool) Migrate(ctx context.Context, migrationsPath string) error {
-	conn := stdlib.OpenDB(*p.pool.Config().ConnConfig)
-
-	driver, err := pgxMigrate.WithInstance(conn, &pgxMigrate.Config{})
-	if err != nil {
-		return MapError(err)
+// tlsOptions - get the TLS options of the connection config
+func tlsOptions(config ConnectionConfig) database.TLSOptions {
+	opts := database.TLSOptions{
+		Mode:         database.SSLMode(config.GetSSLMode()),
+		ServerName:   config.GetHost(),
+		RootCertPath: config.GetSSLRoot(),
+		CertPath:     config.GetSSLCert(),
+		KeyPath:      config.GetSSLKey(),
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://"+migrationsPath,
-		"pgx",
-		driver,
-	)
-	if err != nil {
-		return MapError(err)
+	if c, ok := config.(SSLPasswordConfig); ok {
+		opts.KeyPassword = c.GetSSLPassword()
 	}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return MapError(err)
+	return opts
+}
+
+// newDialer - create dialer with the TCP keepalive settings of the config.
+// Zero keepalive settings fall back to the defaults of net.KeepAliveConfig.
+func newDialer(config PoolConfig, connectTimeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout: connectTimeout,
+		KeepAliveConfig: net.KeepAliveConfig{
+			Enable:   true,
+			Idle:     config.GetMaxConnKeepAliveTime(),
+			Interval: config.GetMaxConnKeepAliveInterval(),
+			Count:    config.GetMaxConnKeepAliveCount(),
+		},
 	}
+}
 
-	if err := conn.Close(); err != nil {
-		return MapError(err)
+// applyQueryExecMode - set the default query exec mode of the connections.
+// Modes without prepared statements disable the statement and description caches.
+func applyQueryExecMode(connConfig *pgx.ConnConfig, mode string) error {
+	switch mode {
+	case QueryExecModeCacheStatement:
+		connConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+	case QueryExecModeCacheDescribe:
+		connConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheDescribe
+	case QueryExecModeDescribeExec:
+		connConfig.DefaultQueryExecMode = pgx.QueryExecModeDescribeExec
+		connConfig.StatementCacheCapacity = 0
+		connConfig.DescriptionCacheCapacity = 0
+	case QueryExecModeExec:
+		connConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+		connConfig.StatementCacheCapacity = 0
+		connConfig.DescriptionCacheCapacity = 0
+	case QueryExecModeSimpleProtocol, QueryExecModePgBouncer:
+		connConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+		connConfig.StatementCacheCapacity = 0
+		connConfig.DescriptionCacheCapacity = 0
+	default:
+		return errors.New(errors.InvalidParameter, "unknown query exec mode: "+mode)
 	}
 
 	return nil
@@ -0,0 +1,281 @@
+// Package queue - durable job queue on PostgreSQL with delayed jobs, priorities, unique jobs,
+// retries with exponential backoff and dead jobs
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	stderrs "errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rlapenok/toolbox/database/postgres"
+	"github.com/rlapenok/toolbox/errors"
+)
+
+// ReasonDuplicateJob - reason of the error of Enqueue when a unique job is already pending or running
+const ReasonDuplicateJob errors.Reason = "duplicate_job"
+
+// Job - job of the queue
+type Job struct {
+	ID          int64           `json:"id"`
+	Queue       string          `json:"queue"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Priority    int             `json:"priority"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	UniqueKey   string          `json:"unique_key,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Handler - handler of the jobs of a kind, an error schedules a retry, a Permanent error makes the job dead
+type Handler func(ctx context.Context, job *Job) error
+
+// permanentError - error of the handler that must not be retried
+type permanentError struct {
+	err error
+}
+
+// Error - implements error
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap - return the error of the handler
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent - mark the error of the handler as not retryable, the job becomes dead immediately
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// IsPermanent - check if the error of the handler is marked by Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return stderrs.As(err, &permanent)
+}
+
+// JSONHandler - handler decoding the JSON payload of the jobs into T.
+// A payload that can't be decoded makes the job dead without retries.
+func JSONHandler[T any](fn func(ctx context.Context, payload T, job *Job) error) Handler {
+	return func(ctx context.Context, job *Job) error {
+		var payload T
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return Permanent(errors.Wrap(err, errors.BadRequest, "invalid job payload").
+				WithReason(errors.ReasonBadRequest))
+		}
+
+		return fn(ctx, payload, job)
+	}
+}
+
+// enqueueOptions - settings of the enqueued job
+type enqueueOptions struct {
+	queue       string
+	priority    int
+	runAt       time.Time
+	maxAttempts int
+	uniqueKey   string
+}
+
+// EnqueueOption - option of the enqueued job
+type EnqueueOption func(*enqueueOptions)
+
+// InQueue - enqueue the job into the queue instead of DefaultQueue
+func InQueue(queue string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.queue = queue
+	}
+}
+
+// WithPriority - set the priority of the job, jobs with a higher priority run first
+func WithPriority(priority int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.priority = priority
+	}
+}
+
+// RunAt - schedule the job at the time
+func RunAt(runAt time.Time) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.runAt = runAt
+	}
+}
+
+// RunIn - schedule the job after the delay
+func RunIn(delay time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.runAt = time.Now().Add(delay)
+	}
+}
+
+// WithMaxAttempts - set the number of attempts before the job is dead
+func WithMaxAttempts(attempts int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.maxAttempts = attempts
+	}
+}
+
+// Unique - enqueue the job only if no pending or running job has the key
+func Unique(key string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.uniqueKey = key
+	}
+}
+
+// IsDuplicate - check if Enqueue failed because the unique job is already enqueued
+func IsDuplicate(err error) bool {
+	tbErr, ok := errors.From(err)
+	return ok && tbErr.Reason() == ReasonDuplicateJob
+}
+
+// Queue - job queue of the pool, it enqueues jobs and runs the workers as a Gracefull
+type Queue struct {
+	pool     *postgres.Pool
+	o        *options
+	handlers map[string]Handler
+	queries  queries
+
+	// ctx - context of the polling, canceled by Stop
+	ctx    context.Context
+	cancel context.CancelFunc
+	// handlerCtx - context of the handlers, canceled when Stop runs out of time
+	handlerCtx    context.Context
+	handlerCancel context.CancelFunc
+
+	done    chan struct{}
+	started atomic.Bool
+}
+
+// New - create new job queue of the pool
+func New(pool *postgres.Pool, opts ...Option) *Queue {
+	o := newOptions(opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handlerCtx, handlerCancel := context.WithCancel(context.Background())
+
+	return &Queue{
+		pool:          pool,
+		o:             o,
+		handlers:      map[string]Handler{},
+		queries:       newQueries(tableIdentifier(o.table)),
+		ctx:           ctx,
+		cancel:        cancel,
+		handlerCtx:    handlerCtx,
+		handlerCancel: handlerCancel,
+		done:          make(chan struct{}),
+	}
+}
+
+// Handle - set the handler of the kind, handlers must be set before Start
+func (q *Queue) Handle(kind string, handler Handler) *Queue {
+	q.handlers[kind] = handler
+
+	return q
+}
+
+// Enqueue - add the job with the payload encoded as JSON.
+// The job is inserted in the transaction of the context if any, so it's visible to workers after the commit.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any, opts ...EnqueueOption) (*Job, error) {
+	o := &enqueueOptions{
+		queue:       DefaultQueue,
+		maxAttempts: defaultMaxAttempts,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if kind == "" {
+		return nil, errors.New(errors.InvalidParameter, "job kind is required").
+			WithReason(errors.ReasonBadRequest)
+	}
+	if o.maxAttempts <= 0 {
+		o.maxAttempts = defaultMaxAttempts
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.InvalidParameter, "failed to encode job payload").
+			WithReason(errors.ReasonBadRequest)
+	}
+
+	var (
+		runAt     *time.Time
+		uniqueKey *string
+	)
+	if !o.runAt.IsZero() {
+		runAt = &o.runAt
+	}
+	if o.uniqueKey != "" {
+		uniqueKey = &o.uniqueKey
+	}
+
+	job := &Job{
+		Queue:       o.queue,
+		Kind:        kind,
+		Payload:     data,
+		Priority:    o.priority,
+		MaxAttempts: o.maxAttempts,
+		UniqueKey:   o.uniqueKey,
+	}
+
+	err = q.pool.Querier(ctx).QueryRow(ctx, q.queries.enqueue,
+		o.queue, kind, string(data), o.priority, o.maxAttempts, runAt, uniqueKey,
+	).Scan(&job.ID, &job.RunAt, &job.CreatedAt)
+	if stderrs.Is(err, pgx.ErrNoRows) {
+		return nil, errors.New(errors.AlreadyExists, "job already enqueued").
+			WithReason(ReasonDuplicateJob).
+			WithInternalDetails(map[string]any{"unique_key": o.uniqueKey, "kind": kind})
+	}
+	if err != nil {
+		return nil, postgres.MapError(err)
+	}
+
+	return job, nil
+}
+
+// Schema - DDL of the jobs table, add it to the migrations of the service.
+// Completed jobs are deleted, jobs out of attempts stay with the status "dead".
+func Schema(table string) string {
+	name := tableIdentifier(table)
+	prefix := strings.ReplaceAll(table, ".", "_")
+
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+	id           BIGSERIAL PRIMARY KEY,
+	queue        TEXT        NOT NULL DEFAULT 'default',
+	kind         TEXT        NOT NULL,
+	payload      JSONB       NOT NULL,
+	priority     INT         NOT NULL DEFAULT 0,
+	status       TEXT        NOT NULL DEFAULT 'pending',
+	attempts     INT         NOT NULL DEFAULT 0,
+	max_attempts INT         NOT NULL,
+	run_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+	locked_until TIMESTAMPTZ,
+	locked_by    UUID,
+	unique_key   TEXT,
+	last_error   TEXT,
+	created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (queue, priority DESC, run_at, id) WHERE status IN ('pending', 'running');
+
+CREATE UNIQUE INDEX IF NOT EXISTS %[3]s ON %[1]s (unique_key) WHERE unique_key IS NOT NULL AND status IN ('pending', 'running');
+`, name, pgx.Identifier{prefix + "_ready_idx"}.Sanitize(), pgx.Identifier{prefix + "_unique_idx"}.Sanitize())
+}
+
+// tableIdentifier - sanitized identifier of the table, "schema.table" is supported
+func tableIdentifier(table string) string {
+	return pgx.Identifier(strings.Split(table, ".")).Sanitize()
+}
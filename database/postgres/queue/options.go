@@ -0,0 +1,144 @@
+package queue
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultTable - default jobs table
+	DefaultTable = "jobs"
+	// DefaultQueue - queue of the jobs enqueued without a queue
+	DefaultQueue = "default"
+
+	// defaultConcurrency - default number of jobs processed at the same time
+	defaultConcurrency = 1
+	// defaultPollInterval - default delay between polls of an empty queue
+	defaultPollInterval = time.Second
+	// defaultVisibilityTimeout - default time after which a running job is claimed again
+	defaultVisibilityTimeout = 5 * time.Minute
+	// defaultMaxAttempts - default number of attempts before the job is dead
+	defaultMaxAttempts = 25
+	// defaultBackoff - default delay before the first retry of a job
+	defaultBackoff = time.Second
+	// defaultMaxBackoff - default maximum delay between retries of a job
+	defaultMaxBackoff = time.Hour
+)
+
+// options - settings of the queue
+type options struct {
+	name              string
+	table             string
+	logger            *zap.Logger
+	queues            []string
+	concurrency       int
+	pollInterval      time.Duration
+	visibilityTimeout time.Duration
+	backoff           time.Duration
+	maxBackoff        time.Duration
+}
+
+// Option - option of the queue
+type Option func(*options)
+
+// WithName - set the name of the workers
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithTable - set the jobs table
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// WithLogger - set the logger of the workers
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithQueues - set the queues processed by the workers (DefaultQueue by default)
+func WithQueues(queues ...string) Option {
+	return func(o *options) {
+		o.queues = queues
+	}
+}
+
+// WithConcurrency - set the number of jobs processed at the same time
+func WithConcurrency(concurrency int) Option {
+	return func(o *options) {
+		o.concurrency = concurrency
+	}
+}
+
+// WithPollInterval - set the delay between polls when the queue has no ready jobs
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = interval
+	}
+}
+
+// WithVisibilityTimeout - set the time a claimed job is hidden from other workers.
+// It's also the timeout of the handler, a job running longer is claimed again.
+func WithVisibilityTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.visibilityTimeout = timeout
+	}
+}
+
+// WithBackoff - set the delay before the first retry and the maximum delay between retries
+func WithBackoff(backoff, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.backoff = backoff
+		o.maxBackoff = maxBackoff
+	}
+}
+
+// newOptions - apply options over the defaults
+func newOptions(opts []Option) *options {
+	o := &options{
+		name:              "job-queue",
+		table:             DefaultTable,
+		logger:            zap.NewNop(),
+		queues:            []string{DefaultQueue},
+		concurrency:       defaultConcurrency,
+		pollInterval:      defaultPollInterval,
+		visibilityTimeout: defaultVisibilityTimeout,
+		backoff:           defaultBackoff,
+		maxBackoff:        defaultMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.logger == nil {
+		o.logger = zap.NewNop()
+	}
+	if len(o.queues) == 0 {
+		o.queues = []string{DefaultQueue}
+	}
+	if o.concurrency <= 0 {
+		o.concurrency = defaultConcurrency
+	}
+	if o.pollInterval <= 0 {
+		o.pollInterval = defaultPollInterval
+	}
+	if o.visibilityTimeout <= 0 {
+		o.visibilityTimeout = defaultVisibilityTimeout
+	}
+	if o.backoff <= 0 {
+		o.backoff = defaultBackoff
+	}
+	if o.maxBackoff <= 0 {
+		o.maxBackoff = defaultMaxBackoff
+	}
+
+	return o
+}
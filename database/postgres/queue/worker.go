@@ -0,0 +1,265 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rlapenok/toolbox/database/postgres"
+	"github.com/rlapenok/toolbox/errors"
+	"github.com/rlapenok/toolbox/routine"
+	"go.uber.org/zap"
+)
+
+// finishTimeout - timeout of recording the result of a job
+const finishTimeout = 5 * time.Second
+
+// queries - queries of the queue for the configured table
+type queries struct {
+	enqueue  string
+	claim    string
+	complete string
+	retry    string
+	dead     string
+	release  string
+}
+
+// newQueries - build the queries of the table
+func newQueries(table string) queries {
+	return queries{
+		enqueue: fmt.Sprintf(`INSERT INTO %s (queue, kind, payload, priority, max_attempts, run_at, unique_key)
+VALUES ($1, $2, $3::jsonb, $4, $5, COALESCE($6::timestamptz, now()), $7)
+ON CONFLICT (unique_key) WHERE unique_key IS NOT NULL AND status IN ('pending', 'running') DO NOTHING
+RETURNING id, run_at, created_at`, table),
+		claim: fmt.Sprintf(`UPDATE %[1]s
+SET status = 'running', attempts = attempts + 1, locked_until = now() + make_interval(secs => $3),
+	locked_by = $4::uuid, updated_at = now()
+WHERE id = (
+	SELECT id FROM %[1]s
+	WHERE queue = ANY($1) AND kind = ANY($2)
+		AND ((status = 'pending' AND run_at <= now()) OR (status = 'running' AND locked_until < now()))
+	ORDER BY priority DESC, run_at, id
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING id, queue, kind, payload, priority, attempts, max_attempts, run_at, COALESCE(unique_key, ''), created_at`, table),
+		complete: fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND locked_by = $2::uuid`, table),
+		retry: fmt.Sprintf(`UPDATE %s
+SET status = 'pending', run_at = now() + make_interval(secs => $3), locked_until = NULL, locked_by = NULL,
+	last_error = $4, updated_at = now()
+WHERE id = $1 AND locked_by = $2::uuid`, table),
+		dead: fmt.Sprintf(`UPDATE %s
+SET status = 'dead', locked_until = NULL, locked_by = NULL, last_error = $3, updated_at = now()
+WHERE id = $1 AND locked_by = $2::uuid`, table),
+		release: fmt.Sprintf(`UPDATE %s
+SET status = 'pending', attempts = attempts - 1, locked_until = NULL, locked_by = NULL, updated_at = now()
+WHERE id = $1 AND locked_by = $2::uuid`, table),
+	}
+}
+
+//===============================================
+// Gracefull
+//===============================================
+
+// Name - return name of the workers
+func (q *Queue) Name() string {
+	return q.o.name
+}
+
+// Address - return address of the database
+func (q *Queue) Address() string {
+	config := q.pool.Pgx().Config().ConnConfig
+
+	return net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port)))
+}
+
+// Start - run the workers until Stop
+func (q *Queue) Start() error {
+	if len(q.handlers) == 0 {
+		return errors.New(errors.InvalidParameter, "job queue has no handlers")
+	}
+
+	// already started or stopped
+	if !q.started.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer close(q.done)
+
+	var wg sync.WaitGroup
+	for range q.o.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = routine.Run(q.ctx, q.o.logger, func(ctx context.Context) error {
+				q.work(ctx)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// Stop - stop claiming jobs and drain the running ones.
+// When ctx is done first, the handlers are canceled and their jobs are released to the queue.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.cancel()
+
+	// the workers were never started
+	if q.started.CompareAndSwap(false, true) {
+		close(q.done)
+	}
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.handlerCancel()
+
+	select {
+	case <-q.done:
+	case <-time.After(finishTimeout):
+	}
+
+	return postgres.MapError(ctx.Err())
+}
+
+// WithLogger - set logger to the workers
+func (q *Queue) WithLogger(logger *zap.Logger) {
+	q.o.logger = logger
+}
+
+// Logger - return logger of the workers
+func (q *Queue) Logger() *zap.Logger {
+	return q.o.logger
+}
+
+// work - claim and process jobs until the polling is stopped
+func (q *Queue) work(ctx context.Context) {
+	kinds := make([]string, 0, len(q.handlers))
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+
+	for ctx.Err() == nil {
+		job, token, err := q.claim(ctx, kinds)
+		if err != nil && ctx.Err() == nil {
+			q.o.logger.Error("failed to claim job", zap.String("name", q.o.name), zap.Error(err))
+		}
+
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(q.o.pollInterval):
+			}
+			continue
+		}
+
+		q.process(job, token)
+	}
+}
+
+// claim - lock the next ready job for the visibility timeout, nil if there is none
+func (q *Queue) claim(ctx context.Context, kinds []string) (*Job, string, error) {
+	token := uuid.NewString()
+	job := &Job{}
+
+	err := q.pool.Pgx().QueryRow(ctx, q.queries.claim,
+		q.o.queues, kinds, q.o.visibilityTimeout.Seconds(), token,
+	).Scan(&job.ID, &job.Queue, &job.Kind, &job.Payload, &job.Priority, &job.Attempts,
+		&job.MaxAttempts, &job.RunAt, &job.UniqueKey, &job.CreatedAt)
+	if err != nil {
+		tbErr := postgres.MapError(err)
+		if tbErr.Code() == errors.NotFound {
+			return nil, "", nil
+		}
+		return nil, "", tbErr
+	}
+
+	return job, token, nil
+}
+
+// process - run the handler of the job and record the result
+func (q *Queue) process(job *Job, token string) {
+	fields := []zap.Field{
+		zap.String("name", q.o.name),
+		zap.Int64("id", job.ID),
+		zap.String("queue", job.Queue),
+		zap.String("kind", job.Kind),
+		zap.Int("attempt", job.Attempts),
+	}
+
+	// the job was claimed again after its last attempt timed out
+	if job.Attempts > job.MaxAttempts {
+		q.o.logger.Error("job is dead", append(fields, zap.String("error", "visibility timeout exceeded"))...)
+		q.finish(job, q.queries.dead, job.ID, token, "visibility timeout exceeded")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(q.handlerCtx, q.o.visibilityTimeout)
+	defer cancel()
+
+	handler := q.handlers[job.Kind]
+	err := routine.Run(ctx, q.o.logger, func(ctx context.Context) error {
+		return handler(ctx, job)
+	})
+
+	switch {
+	case err == nil:
+		q.finish(job, q.queries.complete, job.ID, token)
+
+	case q.handlerCtx.Err() != nil:
+		q.o.logger.Warn("job released on shutdown", fields...)
+		q.finish(job, q.queries.release, job.ID, token)
+
+	case IsPermanent(err), job.Attempts >= job.MaxAttempts:
+		q.o.logger.Error("job is dead", append(fields, zap.Error(err))...)
+		q.finish(job, q.queries.dead, job.ID, token, err.Error())
+
+	default:
+		delay := q.retryDelay(job.Attempts, err)
+		q.o.logger.Warn("job failed", append(fields, zap.Duration("retry_in", delay), zap.Error(err))...)
+		q.finish(job, q.queries.retry, job.ID, token, delay.Seconds(), err.Error())
+	}
+}
+
+// finish - record the result of the job, a lost lock means the job was claimed again and is skipped
+func (q *Queue) finish(job *Job, query string, args ...any) {
+	ctx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer cancel()
+
+	tag, err := q.pool.Pgx().Exec(ctx, query, args...)
+	if err != nil {
+		q.o.logger.Error("failed to record job result",
+			zap.String("name", q.o.name),
+			zap.Int64("id", job.ID),
+			zap.Error(postgres.MapError(err)),
+		)
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		q.o.logger.Warn("job lock lost, the result is skipped",
+			zap.String("name", q.o.name),
+			zap.Int64("id", job.ID),
+		)
+	}
+}
+
+// retryDelay - exponential backoff of the attempt, at least the Retry-After of the handler error
+func (q *Queue) retryDelay(attempts int, err error) time.Duration {
+	delay := postgres.Backoff(q.o.backoff, q.o.maxBackoff, attempts-1)
+
+	if tbErr, ok := errors.From(err); ok && tbErr.RetryAfter() > delay {
+		delay = tbErr.RetryAfter()
+	}
+
+	return delay
+}
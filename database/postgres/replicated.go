@@ -0,0 +1,258 @@
+package postgres
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rlapenok/toolbox/errors"
+	"github.com/rlapenok/toolbox/routine"
+	"go.uber.org/zap"
+)
+
+// Balancing - replica selection strategy of ReplicatedPool
+type Balancing string
+
+const (
+	// BalancingRoundRobin - select replicas in turn
+	BalancingRoundRobin Balancing = "round_robin"
+	// BalancingLeastConnections - select the replica with the least acquired connections
+	BalancingLeastConnections Balancing = "least_connections"
+)
+
+// replicationLagQuery - recovery state, WAL receiver state and replay lag of the standby in seconds.
+// A standby which replayed everything it received is not lagging even if the primary is idle,
+// as long as its WAL receiver is streaming. The receiver status is visible only with
+// pg_read_all_stats, without it a running receiver is taken as streaming.
+const replicationLagQuery = `SELECT pg_is_in_recovery(), r.streaming, CASE
+	WHEN NOT pg_is_in_recovery() THEN 0
+	WHEN r.streaming AND pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END::float8
+FROM (SELECT EXISTS (
+	SELECT 1 FROM pg_stat_wal_receiver WHERE COALESCE(status, 'streaming') = 'streaming'
+) AS streaming) r`
+
+// readOnlyKey - context key of the read-only mark
+type readOnlyKey struct{}
+
+// WithReadOnly - mark the queries of the context as read-only, ReplicatedPool routes them to replicas
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// IsReadOnly - check if the queries of the context are marked as read-only
+func IsReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey{}).(bool)
+	return readOnly
+}
+
+// replica - replica pool with the result of the last check
+type replica struct {
+	pool      *Pool
+	available atomic.Bool
+}
+
+// ReplicatedPool - primary pool with read replicas.
+// Replicas failing the health check, promoted, not streaming WAL or lagging more than the maximum lag are excluded,
+// reads fall back to the primary when no replica is available.
+type ReplicatedPool struct {
+	primary  *Pool
+	replicas []*replica
+
+	logger    *zap.Logger
+	balancing Balancing
+	maxLag    time.Duration
+	period    time.Duration
+	next      atomic.Uint64
+
+	cancel context.CancelFunc
+	done   <-chan error
+	once   sync.Once
+}
+
+// NewReplicatedPool - create new pool of the primary and the replicas
+func NewReplicatedPool(ctx context.Context, primary PoolConfig, replicas []PoolConfig, opts ...Option) (*ReplicatedPool, error) {
+	o := newOptions(opts)
+
+	switch o.balancing {
+	case BalancingRoundRobin, BalancingLeastConnections:
+	default:
+		return nil, errors.New(errors.InvalidParameter, "unknown balancing: "+string(o.balancing))
+	}
+
+	primaryPool, err := NewPool(ctx, primary, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ReplicatedPool{
+		primary:   primaryPool,
+		replicas:  make([]*replica, 0, len(replicas)),
+		logger:    o.logger,
+		balancing: o.balancing,
+		maxLag:    o.maxReplicationLag,
+		period:    o.replicaCheckPeriod,
+	}
+
+	for _, config := range replicas {
+		pool, err := NewPool(ctx, config, opts...)
+		if err != nil {
+			p.closePools()
+			return nil, err
+		}
+		p.replicas = append(p.replicas, &replica{pool: pool})
+	}
+
+	// check replicas before the first query
+	p.checkReplicas(ctx)
+
+	checkCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	p.cancel = cancel
+	p.done = routine.Go(checkCtx, p.logger, p.runChecks)
+
+	return p, nil
+}
+
+// Writer - get the primary pool
+func (p *ReplicatedPool) Writer() *Pool {
+	return p.primary
+}
+
+// Reader - get an available replica pool or the primary pool if there is none
+func (p *ReplicatedPool) Reader() *Pool {
+	available := make([]*replica, 0, len(p.replicas))
+	for _, r := range p.replicas {
+		if r.available.Load() {
+			available = append(available, r)
+		}
+	}
+
+	if len(available) == 0 {
+		return p.primary
+	}
+
+	if p.balancing == BalancingLeastConnections {
+		selected := available[0]
+		for _, r := range available[1:] {
+			if r.pool.pool.Stat().AcquiredConns() < selected.pool.pool.Stat().AcquiredConns() {
+				selected = r
+			}
+		}
+		return selected.pool
+	}
+
+	return available[(p.next.Add(1)-1)%uint64(len(available))].pool
+}
+
+// Querier - get the transaction of the context, a reader for read-only contexts or the writer otherwise
+func (p *ReplicatedPool) Querier(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+
+	if IsReadOnly(ctx) {
+		return p.Reader().pool
+	}
+
+	return p.primary.pool
+}
+
+// WithTx - run fn in a transaction, see Pool.WithTx.
+// Read-only transactions in a read-only context run on a reader, others on the writer.
+func (p *ReplicatedPool) WithTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context) error) error {
+	if _, ok := TxFromContext(ctx); !ok && opts != nil && opts.AccessMode == pgx.ReadOnly && IsReadOnly(ctx) {
+		return p.Reader().WithTx(ctx, opts, fn)
+	}
+
+	return p.primary.WithTx(ctx, opts, fn)
+}
+
+// Close - stop the replica checks and close all pools
+func (p *ReplicatedPool) Close() {
+	p.once.Do(func() {
+		p.cancel()
+		<-p.done
+		p.closePools()
+	})
+}
+
+// closePools - close the primary and replica pools
+func (p *ReplicatedPool) closePools() {
+	p.primary.Close()
+	for _, r := range p.replicas {
+		r.pool.Close()
+	}
+}
+
+// runChecks - check the replicas periodically until the context is done
+func (p *ReplicatedPool) runChecks(ctx context.Context) error {
+	ticker := time.NewTicker(p.period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			p.checkReplicas(ctx)
+		}
+	}
+}
+
+// checkReplicas - update the availability of the replicas
+func (p *ReplicatedPool) checkReplicas(ctx context.Context) {
+	results := make([]<-chan error, 0, len(p.replicas))
+	for i, r := range p.replicas {
+		results = append(results, routine.Go(ctx, p.logger, func(ctx context.Context) error {
+			p.checkReplica(ctx, i, r)
+			return nil
+		}))
+	}
+
+	// a panic of a check is logged by routine, the availability of the replica is kept
+	for _, result := range results {
+		<-result
+	}
+}
+
+// checkReplica - check the health, the recovery state and the replication lag of the replica.
+// A promoted replica or a replica whose WAL receiver is not streaming is unavailable.
+func (p *ReplicatedPool) checkReplica(ctx context.Context, index int, r *replica) {
+	checkCtx, cancel := context.WithTimeout(ctx, p.period)
+	defer cancel()
+
+	var (
+		inRecovery bool
+		streaming  bool
+		seconds    float64
+	)
+	err := r.pool.pool.QueryRow(checkCtx, replicationLagQuery).Scan(&inRecovery, &streaming, &seconds)
+	if ctx.Err() != nil {
+		return
+	}
+
+	lag := time.Duration(math.Round(seconds * float64(time.Second)))
+
+	available := err == nil && inRecovery && streaming && (p.maxLag <= 0 || lag <= p.maxLag)
+	if r.available.Swap(available) == available {
+		return
+	}
+
+	fields := []zap.Field{zap.Int("replica", index), zap.Duration("lag", lag)}
+	switch {
+	case err != nil:
+		p.logger.Warn("replica is unavailable", append(fields, zap.Error(MapError(err)))...)
+	case !inRecovery:
+		p.logger.Warn("replica is not in recovery, it was promoted", fields...)
+	case !streaming:
+		p.logger.Warn("replica is not streaming WAL from the primary", fields...)
+	case !available:
+		p.logger.Warn("replica is lagging", append(fields, zap.Duration("max_lag", p.maxLag))...)
+	default:
+		p.logger.Info("replica is available", fields...)
+	}
+}
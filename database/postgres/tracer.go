@@ -0,0 +1,258 @@
+package postgres
+
+import (
+	"context"
+	stderrs "errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rlapenok/toolbox/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultSlowQueryThreshold - default duration after which a query is logged as slow
+	defaultSlowQueryThreshold = time.Second
+	// redacted - placeholder of a redacted argument
+	redacted = "[REDACTED]"
+)
+
+// ArgsRedactor - replace sensitive arguments of the query before logging
+type ArgsRedactor func(sql string, args []any) []any
+
+// RedactAllArgs - replace every argument with a placeholder
+func RedactAllArgs(_ string, args []any) []any {
+	result := make([]any, len(args))
+	for i := range args {
+		result[i] = redacted
+	}
+
+	return result
+}
+
+// RedactStringArgs - replace strings and bytes (passwords, emails, names, etc.) with a placeholder,
+// keep numbers, booleans, times, uuids and NULLs
+func RedactStringArgs(_ string, args []any) []any {
+	result := make([]any, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64,
+			time.Time, time.Duration, uuid.UUID:
+			result[i] = v
+		default:
+			result[i] = redacted
+		}
+	}
+
+	return result
+}
+
+// QueryLoggerOption - option of the query logger
+type QueryLoggerOption func(*QueryLogger)
+
+// WithSlowQueryThreshold - log queries, batches and copies longer than threshold with the warn level.
+// Zero or negative threshold disables slow query detection.
+func WithSlowQueryThreshold(threshold time.Duration) QueryLoggerOption {
+	return func(l *QueryLogger) {
+		l.slowThreshold = threshold
+	}
+}
+
+// WithQueryArgs - log the arguments of queries through redact, RedactStringArgs if redact is nil.
+// Only the number of arguments is logged without this option.
+func WithQueryArgs(redact ArgsRedactor) QueryLoggerOption {
+	return func(l *QueryLogger) {
+		if redact == nil {
+			redact = RedactStringArgs
+		}
+		l.redact = redact
+	}
+}
+
+// QueryLogger - pgx tracer logging queries, batches, copies and connects through zap.
+// Successful operations are logged with the debug level, slow ones with the warn level
+// and failed ones with the error level.
+type QueryLogger struct {
+	logger        *zap.Logger
+	slowThreshold time.Duration
+	redact        ArgsRedactor
+}
+
+// NewQueryLogger - create new query logger
+func NewQueryLogger(logger *zap.Logger, opts ...QueryLoggerOption) *QueryLogger {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	l := &QueryLogger{
+		logger:        logger,
+		slowThreshold: defaultSlowQueryThreshold,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// traceKey - context key of the trace data
+type traceKey struct{}
+
+// traceData - data of the traced operation stored in the context between start and end
+type traceData struct {
+	start  time.Time
+	fields []zap.Field
+}
+
+// TraceQueryStart - implements pgx.QueryTracer
+func (l *QueryLogger) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return l.start(ctx, append([]zap.Field{zap.String("sql", data.SQL)}, l.argsFields(data.SQL, data.Args)...))
+}
+
+// TraceQueryEnd - implements pgx.QueryTracer
+func (l *QueryLogger) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	l.end(ctx, "query", data.CommandTag, data.Err)
+}
+
+// TraceBatchStart - implements pgx.BatchTracer
+func (l *QueryLogger) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	return l.start(ctx, []zap.Field{zap.Int("batch_len", data.Batch.Len())})
+}
+
+// TraceBatchQuery - implements pgx.BatchTracer
+func (l *QueryLogger) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	fields := append([]zap.Field{zap.String("sql", data.SQL)}, l.argsFields(data.SQL, data.Args)...)
+	fields = append(fields, zap.Int64("rows", data.CommandTag.RowsAffected()))
+	fields = append(fields, l.contextFields(ctx)...)
+
+	if data.Err != nil {
+		l.logger.Error("batch query failed", append(fields, errorFields(data.Err)...)...)
+		return
+	}
+
+	l.logger.Debug("batch query", fields...)
+}
+
+// TraceBatchEnd - implements pgx.BatchTracer
+func (l *QueryLogger) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	l.end(ctx, "batch", pgconn.CommandTag{}, data.Err)
+}
+
+// TraceCopyFromStart - implements pgx.CopyFromTracer
+func (l *QueryLogger) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	return l.start(ctx, []zap.Field{
+		zap.String("table", data.TableName.Sanitize()),
+		zap.Strings("columns", data.ColumnNames),
+	})
+}
+
+// TraceCopyFromEnd - implements pgx.CopyFromTracer
+func (l *QueryLogger) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	l.end(ctx, "copy", data.CommandTag, data.Err)
+}
+
+// TraceConnectStart - implements pgx.ConnectTracer
+func (l *QueryLogger) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	return l.start(ctx, []zap.Field{
+		zap.String("host", data.ConnConfig.Host),
+		zap.Uint16("port", data.ConnConfig.Port),
+		zap.String("database", data.ConnConfig.Database),
+	})
+}
+
+// TraceConnectEnd - implements pgx.ConnectTracer
+func (l *QueryLogger) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	trace, ok := ctx.Value(traceKey{}).(*traceData)
+	if !ok {
+		return
+	}
+
+	fields := append(trace.fields, zap.Duration("duration", time.Since(trace.start)))
+	fields = append(fields, l.contextFields(ctx)...)
+
+	if data.Err != nil {
+		l.logger.Error("connect failed", append(fields, errorFields(data.Err)...)...)
+		return
+	}
+
+	l.logger.Debug("connect", fields...)
+}
+
+// start - store the start time and the fields of the operation in the context
+func (l *QueryLogger) start(ctx context.Context, fields []zap.Field) context.Context {
+	return context.WithValue(ctx, traceKey{}, &traceData{start: time.Now(), fields: fields})
+}
+
+// end - log the operation with its duration, rows and error
+func (l *QueryLogger) end(ctx context.Context, operation string, tag pgconn.CommandTag, err error) {
+	trace, ok := ctx.Value(traceKey{}).(*traceData)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(trace.start)
+	fields := append(trace.fields, zap.Duration("duration", duration))
+	if err == nil {
+		fields = append(fields, zap.Int64("rows", tag.RowsAffected()))
+	}
+	fields = append(fields, l.contextFields(ctx)...)
+
+	slow := l.slowThreshold > 0 && duration >= l.slowThreshold
+	if slow {
+		fields = append(fields, zap.Bool("slow", true))
+	}
+
+	switch {
+	case err != nil:
+		l.logger.Error(operation+" failed", append(fields, errorFields(err)...)...)
+	case slow:
+		l.logger.Warn("slow "+operation, fields...)
+	default:
+		l.logger.Debug(operation, fields...)
+	}
+}
+
+// argsFields - fields of the query arguments
+func (l *QueryLogger) argsFields(sql string, args []any) []zap.Field {
+	if l.redact == nil {
+		return []zap.Field{zap.Int("args_count", len(args))}
+	}
+
+	redactedArgs := l.redact(sql, args)
+	values := make([]string, len(redactedArgs))
+	for i, arg := range redactedArgs {
+		values[i] = fmt.Sprintf("%v", arg)
+	}
+
+	return []zap.Field{zap.Strings("args", values)}
+}
+
+// contextFields - fields of the context (request id)
+func (l *QueryLogger) contextFields(ctx context.Context) []zap.Field {
+	if requestID, ok := logger.RequestIDFromContext(ctx); ok {
+		return []zap.Field{zap.String(logger.RequestIDKey, requestID)}
+	}
+
+	return nil
+}
+
+// errorFields - fields of the error mapped to the toolbox error
+func errorFields(err error) []zap.Field {
+	tbErr := MapError(err)
+	fields := []zap.Field{
+		zap.Error(err),
+		zap.String("code", tbErr.Code().String()),
+		zap.String("reason", string(tbErr.Reason())),
+	}
+
+	var pgErr *pgconn.PgError
+	if stderrs.As(err, &pgErr) {
+		fields = append(fields, zap.String("sqlstate", pgErr.Code))
+	}
+
+	return fields
+}
@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"context"
+	stderrs "errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// defaultTxMaxRetries - default number of retries of a transaction
+	defaultTxMaxRetries = 3
+	// defaultTxRetryBackoff - default delay before the first retry of a transaction
+	defaultTxRetryBackoff = 20 * time.Millisecond
+	// defaultTxMaxRetryBackoff - default maximum delay between retries of a transaction
+	defaultTxMaxRetryBackoff = time.Second
+)
+
+// txKey - context key of the current transaction
+type txKey struct{}
+
+// Querier - common interface of the pool and a transaction used by repositories
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// TxOptions - options of a transaction started by WithTx
+type TxOptions struct {
+	// IsoLevel - isolation level, the server default if empty
+	IsoLevel pgx.TxIsoLevel
+	// AccessMode - read write or read only
+	AccessMode pgx.TxAccessMode
+	// DeferrableMode - deferrable mode, meaningful for serializable read only transactions
+	DeferrableMode pgx.TxDeferrableMode
+
+	// MaxRetries - number of retries on serialization failures and deadlocks, negative disables retries
+	MaxRetries int
+	// RetryBackoff - delay before the first retry, doubled on every next retry
+	RetryBackoff time.Duration
+	// MaxRetryBackoff - maximum delay between retries
+	MaxRetryBackoff time.Duration
+}
+
+// TxFromContext - get the transaction stored in the context by WithTx
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// Querier - get the transaction of the context or the pool if there is none
+func (p *Pool) Querier(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+
+	return p.pool
+}
+
+// WithTx - run fn in a transaction stored in the context passed to fn.
+// The transaction is committed if fn returns nil and rolled back otherwise.
+// A call inside another transaction creates a savepoint, opts are ignored in this case.
+// Serialization failures (40001) and deadlocks (40P01) of a top-level transaction
+// are retried with exponential backoff, so fn must be safe to run again.
+func (p *Pool) WithTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context) error) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		return MapError(runTx(ctx, tx, fn))
+	}
+
+	if opts == nil {
+		opts = &TxOptions{}
+	}
+
+	maxRetries := opts.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultTxMaxRetries
+	}
+
+	txOptions := pgx.TxOptions{
+		IsoLevel:       opts.IsoLevel,
+		AccessMode:     opts.AccessMode,
+		DeferrableMode: opts.DeferrableMode,
+	}
+
+	for attempt := 0; ; attempt++ {
+		tx, err := p.pool.BeginTx(ctx, txOptions)
+		if err != nil {
+			return MapError(err)
+		}
+
+		err = runTx(ctx, tx, fn)
+		if err == nil || attempt >= maxRetries || !isRetryableTxError(err) {
+			return MapError(err)
+		}
+
+		if err := sleep(ctx, txBackoff(opts, attempt)); err != nil {
+			return MapError(err)
+		}
+	}
+}
+
+// runTx - run fn in the transaction (or savepoint) tx and commit or roll it back
+func runTx(ctx context.Context, tx pgx.Tx, fn func(ctx context.Context) error) (err error) {
+	// a nested transaction is a savepoint of the current one
+	if _, ok := TxFromContext(ctx); ok {
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		tx = savepoint
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+			panic(r)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if rollbackErr := tx.Rollback(context.WithoutCancel(ctx)); rollbackErr != nil && !stderrs.Is(rollbackErr, pgx.ErrTxClosed) {
+			return stderrs.Join(err, rollbackErr)
+		}
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// isRetryableTxError - check if the transaction failed with a serialization failure or a deadlock
+func isRetryableTxError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !stderrs.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// txBackoff - exponential backoff with jitter before the retry
+func txBackoff(opts *TxOptions, attempt int) time.Duration {
+	backoff := opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultTxRetryBackoff
+	}
+
+	maxBackoff := opts.MaxRetryBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultTxMaxRetryBackoff
+	}
+
+	return expBackoff(backoff, maxBackoff, attempt)
+}
+
+// Backoff - exponential backoff: base doubled on every attempt (starting from 0) up to maxDelay.
+// The base is compared with maxDelay shifted back, so a large attempt never overflows.
+func Backoff(base, maxDelay time.Duration, attempt int) time.Duration {
+	if attempt >= 0 && attempt < 63 && base <= maxDelay>>attempt {
+		return base << attempt
+	}
+
+	return maxDelay
+}
+
+// expBackoff - exponential backoff with jitter
+func expBackoff(base, maxDelay time.Duration, attempt int) time.Duration {
+	delay := Backoff(base, maxDelay, attempt)
+
+	// equal jitter in [delay/2, delay]
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// sleep - wait for the delay or the context cancellation
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,325 @@
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rlapenok/toolbox/errors"
+)
+
+// SSLMode - libpq sslmode
+type SSLMode string
+
+// SSL modes with the semantics of libpq: https://www.postgresql.org/docs/current/libpq-ssl.html
+const (
+	// SSLModeDisable - plaintext only
+	SSLModeDisable SSLMode = "disable"
+	// SSLModeAllow - plaintext first, TLS without verification if plaintext fails
+	SSLModeAllow SSLMode = "allow"
+	// SSLModePrefer - TLS without verification first, plaintext if TLS fails (libpq default)
+	SSLModePrefer SSLMode = "prefer"
+	// SSLModeRequire - TLS without verification, verify-ca if a root certificate is set
+	SSLModeRequire SSLMode = "require"
+	// SSLModeVerifyCA - TLS with verification of the server certificate chain
+	SSLModeVerifyCA SSLMode = "verify-ca"
+	// SSLModeVerifyFull - TLS with verification of the server certificate chain and host name
+	SSLModeVerifyFull SSLMode = "verify-full"
+)
+
+// SSLRootCertSystem - value of the root certificate path that selects the system certificate pool
+const SSLRootCertSystem = "system"
+
+// defaultRootCertPath - root certificate path of libpq relative to the home directory
+var defaultRootCertPath = filepath.Join(".postgresql", "root.crt")
+
+// TLSOptions - options of the TLS connection to the database
+type TLSOptions struct {
+	// Mode - libpq sslmode, SSLModePrefer if empty
+	Mode SSLMode
+	// ServerName - host name verified in verify-full mode and sent as SNI
+	ServerName string
+	// RootCertPath - path to the CA certificates (sslrootcert), ~/.postgresql/root.crt if empty
+	// like in libpq, SSLRootCertSystem selects the system pool
+	RootCertPath string
+	// CertPath - path to the client certificate (sslcert)
+	CertPath string
+	// KeyPath - path to the client key (sslkey)
+	KeyPath string
+	// KeyPassword - password of a client key with the legacy PEM encryption (sslpassword)
+	KeyPassword string
+}
+
+// BuildTLSConfigs - build the TLS configs to try in order, nil stands for a plaintext connection.
+// disable: [nil], allow: [nil, tls], prefer: [tls, nil], require, verify-ca and verify-full: [tls].
+// The certificate files are reloaded when they change, so rotated certificates are used
+// by new connections without recreating the pool.
+func BuildTLSConfigs(opts TLSOptions) ([]*tls.Config, error) {
+	mode := opts.Mode
+	if mode == "" {
+		mode = SSLModePrefer
+	}
+
+	// libpq: require behaves like verify-ca when a root certificate is set
+	if mode == SSLModeRequire && opts.RootCertPath != "" {
+		mode = SSLModeVerifyCA
+	}
+
+	switch mode {
+	case SSLModeDisable:
+		return []*tls.Config{nil}, nil
+	case SSLModeAllow, SSLModePrefer, SSLModeRequire, SSLModeVerifyCA, SSLModeVerifyFull:
+	default:
+		return nil, errors.New(errors.InvalidParameter, "invalid sslmode: "+string(mode))
+	}
+
+	if mode == SSLModeVerifyFull && opts.ServerName == "" {
+		return nil, errors.New(errors.InvalidParameter, "server name is required for sslmode verify-full")
+	}
+
+	if (opts.CertPath == "") != (opts.KeyPath == "") {
+		return nil, errors.New(errors.InvalidParameter, "both client certificate and key are required")
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.ServerName,
+		// the server certificate is verified by VerifyConnection with the reloadable root certificates
+		InsecureSkipVerify: true,
+	}
+
+	if mode == SSLModeVerifyCA || mode == SSLModeVerifyFull {
+		rootCertPath := opts.RootCertPath
+		if rootCertPath == "" {
+			// libpq fails if the default root certificate is missing instead of trusting the system pool
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, errors.Wrap(err, errors.InvalidParameter, "root certificate is required for sslmode "+string(mode))
+			}
+			rootCertPath = filepath.Join(home, defaultRootCertPath)
+			if _, err := os.Stat(rootCertPath); err != nil {
+				return nil, errors.Wrap(err, errors.InvalidParameter,
+					"root certificate "+rootCertPath+" is required for sslmode "+string(mode)+
+						", set the root certificate path or \""+SSLRootCertSystem+"\" to trust the system pool")
+			}
+		}
+
+		roots := &rootsReloader{path: rootCertPath}
+		if _, err := roots.pool(); err != nil {
+			return nil, err
+		}
+
+		// the configured host, state.ServerName is empty for IP addresses (they are not sent as SNI)
+		var verifyHost string
+		if mode == SSLModeVerifyFull {
+			verifyHost = opts.ServerName
+		}
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifyServer(state, roots, verifyHost)
+		}
+	}
+
+	if opts.CertPath != "" {
+		cert := &certReloader{
+			certPath: opts.CertPath,
+			keyPath:  opts.KeyPath,
+			password: opts.KeyPassword,
+		}
+		if _, err := cert.certificate(); err != nil {
+			return nil, err
+		}
+
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return cert.certificate()
+		}
+	}
+
+	switch mode {
+	case SSLModeAllow:
+		return []*tls.Config{nil, tlsConfig}, nil
+	case SSLModePrefer:
+		return []*tls.Config{tlsConfig, nil}, nil
+	default:
+		return []*tls.Config{tlsConfig}, nil
+	}
+}
+
+// verifyServer - verify the certificate chain of the server and its host name (DNS name or IP address)
+// if host is not empty
+func verifyServer(state tls.ConnectionState, roots *rootsReloader, host string) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New(errors.Unavailable, "server did not present a certificate")
+	}
+
+	pool, err := roots.pool()
+	if err != nil {
+		return err
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: x509.NewCertPool(),
+	}
+	opts.DNSName = host
+
+	// the first certificate is the leaf, all others are intermediates
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	if _, err := state.PeerCertificates[0].Verify(opts); err != nil {
+		return errors.Wrap(err, errors.Unavailable, "failed to verify server certificate: "+err.Error())
+	}
+
+	return nil
+}
+
+// fileVersion - modification time and size of a file, used to detect rotation
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// statFile - get the version of the file
+func statFile(path string) (fileVersion, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}, errors.Wrap(err, errors.Internal, err.Error())
+	}
+
+	return fileVersion{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// rootsReloader - root certificates reloaded when the file changes
+type rootsReloader struct {
+	path string
+
+	mu      sync.Mutex
+	version fileVersion
+	cached  *x509.CertPool
+}
+
+// pool - get the root certificates, reloading them if the file changed
+func (r *rootsReloader) pool() (*x509.CertPool, error) {
+	if r.path == SSLRootCertSystem {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, errors.Wrap(err, errors.Internal, err.Error())
+		}
+		return pool, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version, err := statFile(r.path)
+	if err != nil {
+		return nil, err
+	}
+	if r.cached != nil && version == r.version {
+		return r.cached, nil
+	}
+
+	caBytes, err := os.ReadFile(r.path)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.Internal, err.Error())
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBytes) {
+		return nil, errors.New(errors.Internal, "invalid CA PEM")
+	}
+
+	r.cached = pool
+	r.version = version
+
+	return pool, nil
+}
+
+// certReloader - client certificate reloaded when the certificate or key file changes
+type certReloader struct {
+	certPath string
+	keyPath  string
+	password string
+
+	mu          sync.Mutex
+	certVersion fileVersion
+	keyVersion  fileVersion
+	cached      *tls.Certificate
+}
+
+// certificate - get the client certificate, reloading it if the files changed
+func (r *certReloader) certificate() (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	certVersion, err := statFile(r.certPath)
+	if err != nil {
+		return nil, err
+	}
+	keyVersion, err := statFile(r.keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if r.cached != nil && certVersion == r.certVersion && keyVersion == r.keyVersion {
+		return r.cached, nil
+	}
+
+	cert, err := loadKeyPair(r.certPath, r.keyPath, r.password)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cached = cert
+	r.certVersion = certVersion
+	r.keyVersion = keyVersion
+
+	return cert, nil
+}
+
+// loadKeyPair - load the client certificate and key, decrypting the key with the password
+func loadKeyPair(certPath, keyPath, password string) (*tls.Certificate, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.Internal, err.Error())
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.Internal, err.Error())
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New(errors.Internal, "invalid key PEM")
+	}
+
+	// encrypted PKCS#8 keys are not supported by the standard library, decrypt them with openssl
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return nil, errors.New(errors.InvalidParameter, "encrypted PKCS#8 keys are not supported, use an unencrypted PKCS#8 key")
+	}
+
+	// the legacy PEM encryption (DEK-Info header) of libpq keys is deprecated and insecure:
+	// it is unauthenticated, so a wrong password may go undetected. It's supported only for
+	// compatibility with existing sslpassword setups, prefer unencrypted keys with restricted file permissions.
+	if x509.IsEncryptedPEMBlock(block) {
+		if password == "" {
+			return nil, errors.New(errors.Internal, "key is encrypted but no password is set")
+		}
+
+		decrypted, err := x509.DecryptPEMBlock(block, []byte(password))
+		if err != nil {
+			return nil, errors.Wrap(err, errors.Internal, "failed to decrypt key: "+err.Error())
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: decrypted})
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.Internal, err.Error())
+	}
+
+	return &cert, nil
+}
@@ -2,37 +2,20 @@ package database
 
 import (
 	"crypto/tls"
-	"crypto/x509"
-	"os"
-
-	"github.com/rlapenok/toolbox/errors"
 )
 
 // BuildTLSConfig Создает конфигурацию TLS
+// с семантикой sslmode=require libpq: сертификат сервера проверяется, только если задан caPath
 func BuildTLSConfig(caPath, certPath, keyPath string) (*tls.Config, error) {
-	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	configs, err := BuildTLSConfigs(TLSOptions{
+		Mode:         SSLModeRequire,
+		RootCertPath: caPath,
+		CertPath:     certPath,
+		KeyPath:      keyPath,
+	})
 	if err != nil {
-		return nil, errors.New(errors.Internal, err.Error())
-	}
-
-	tlsConfig := &tls.Config{
-		Certificates:       []tls.Certificate{cert},
-		MinVersion:         tls.VersionTLS12,
-		InsecureSkipVerify: true,
-	}
-
-	if caPath != "" {
-		caBytes, err := os.ReadFile(caPath)
-		if err != nil {
-			return nil, errors.New(errors.Internal, err.Error())
-		}
-		caPool := x509.NewCertPool()
-		if !caPool.AppendCertsFromPEM(caBytes) {
-			return nil, errors.New(errors.Internal, "invalid CA PEM")
-		}
-		tlsConfig.RootCAs = caPool
-		tlsConfig.InsecureSkipVerify = false
+		return nil, err
 	}
 
-	return tlsConfig, nil
+	return configs[0], nil
 }
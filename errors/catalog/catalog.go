@@ -0,0 +1,198 @@
+// Package catalog - declarative catalog of domain errors and generator of their constructors
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rlapenok/toolbox/errors"
+	"google.golang.org/grpc/codes"
+	"gopkg.in/yaml.v3"
+)
+
+// reservedParams - names used by the generated constructors
+var reservedParams = map[string]bool{
+	"errors":  true,
+	"params":  true,
+	"details": true,
+}
+
+// Catalog - description of the domain errors of a service
+type Catalog struct {
+	// Package - name of the generated Go package
+	Package string `yaml:"package" json:"package"`
+	// Service - service that returns the errors
+	Service string `yaml:"service" json:"service"`
+	// Domain - default domain of the errors
+	Domain string `yaml:"domain" json:"domain"`
+	// Errors - the errors of the catalog
+	Errors []Entry `yaml:"errors" json:"errors"`
+}
+
+// Entry - description of a single error
+type Entry struct {
+	// ID - stable identifier of the error, used as the name of the Go constructor
+	ID string `yaml:"id" json:"id"`
+	// Code - name (e.g. "NotFound") or number (e.g. "404") of the toolbox error code
+	Code string `yaml:"code" json:"code"`
+	// Reason - machine readable reason, unique in the catalog
+	Reason string `yaml:"reason" json:"reason"`
+	// Domain - domain of the error, overrides the domain of the catalog
+	Domain string `yaml:"domain" json:"domain"`
+	// Message - developer message template
+	Message string `yaml:"message" json:"message"`
+	// Messages - client message templates keyed by locale
+	Messages map[string]string `yaml:"messages" json:"messages"`
+	// Description - documentation of the error
+	Description string `yaml:"description" json:"description"`
+}
+
+// Load - read the catalog from a YAML or JSON file
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.InvalidParameter, err.Error())
+	}
+
+	return Parse(data)
+}
+
+// Parse - parse the catalog from YAML or JSON and validate it
+func Parse(data []byte) (*Catalog, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	catalog := &Catalog{}
+	if err := decoder.Decode(catalog); err != nil {
+		return nil, errors.Wrap(err, errors.InvalidParameter, "invalid catalog: "+err.Error())
+	}
+
+	if err := catalog.Validate(); err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
+
+// Validate - check that ids and reasons are unique and every code maps to a known HTTP and GRPC status
+func (c *Catalog) Validate() error {
+	var problems []string
+
+	if c.Package != "" && !token.IsIdentifier(c.Package) {
+		problems = append(problems, fmt.Sprintf("package %q is not a valid Go identifier", c.Package))
+	}
+
+	if len(c.Errors) == 0 {
+		problems = append(problems, "catalog has no errors")
+	}
+
+	ids := make(map[string]bool, len(c.Errors))
+	reasons := make(map[string]string, len(c.Errors))
+
+	for i, entry := range c.Errors {
+		name := entry.ID
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+
+		switch {
+		case entry.ID == "":
+			problems = append(problems, fmt.Sprintf("error %s: id is required", name))
+		case !token.IsIdentifier(entry.ID) || !token.IsExported(entry.ID):
+			problems = append(problems, fmt.Sprintf("error %s: id must be an exported Go identifier", name))
+		case ids[entry.ID]:
+			problems = append(problems, fmt.Sprintf("error %s: duplicate id", name))
+		}
+		ids[entry.ID] = true
+
+		if entry.Reason == "" {
+			problems = append(problems, fmt.Sprintf("error %s: reason is required", name))
+		} else if other, ok := reasons[entry.Reason]; ok {
+			problems = append(problems, fmt.Sprintf("error %s: reason %q is already used by %s", name, entry.Reason, other))
+		} else {
+			reasons[entry.Reason] = name
+		}
+
+		code, ok := errors.ParseCode(entry.Code)
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("error %s: unknown code %q", name, entry.Code))
+		case code.HTTPStatus() < http.StatusBadRequest:
+			problems = append(problems, fmt.Sprintf("error %s: code %s has no HTTP error status", name, code))
+		case code.GRPCCode() == codes.Unknown:
+			problems = append(problems, fmt.Sprintf("error %s: code %s has no GRPC code", name, code))
+		}
+
+		if entry.Message == "" {
+			problems = append(problems, fmt.Sprintf("error %s: message is required", name))
+		}
+
+		for _, param := range entry.Params() {
+			switch {
+			case !token.IsIdentifier(param) || token.IsKeyword(param):
+				problems = append(problems, fmt.Sprintf("error %s: parameter %q is not a valid Go identifier", name, param))
+			case reservedParams[param]:
+				problems = append(problems, fmt.Sprintf("error %s: parameter %q is reserved by the generator", name, param))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(errors.InvalidParameter, "invalid catalog:\n  "+strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// Params - names of the template parameters of the error in order of appearance
+func (e *Entry) Params() []string {
+	var params []string
+	seen := make(map[string]bool)
+
+	add := func(template string) {
+		for _, name := range errors.Placeholders(template) {
+			if !seen[name] {
+				seen[name] = true
+				params = append(params, name)
+			}
+		}
+	}
+
+	add(e.Message)
+	for _, locale := range e.Locales() {
+		add(e.Messages[locale])
+	}
+
+	return params
+}
+
+// Locales - sorted locales of the client messages
+func (e *Entry) Locales() []string {
+	locales := make([]string, 0, len(e.Messages))
+	for locale := range e.Messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	return locales
+}
+
+// ErrorCode - toolbox code of the error, the catalog must be validated
+func (e *Entry) ErrorCode() errors.Code {
+	code, _ := errors.ParseCode(e.Code)
+	return code
+}
+
+// DomainOf - domain of the error, falling back to the domain of the catalog
+func (c *Catalog) DomainOf(e *Entry) string {
+	if e.Domain != "" {
+		return e.Domain
+	}
+
+	return c.Domain
+}
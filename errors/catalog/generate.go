@@ -0,0 +1,217 @@
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/rlapenok/toolbox/errors"
+)
+
+// goTemplate - template of the generated Go file
+var goTemplate = template.Must(template.New("go").Funcs(template.FuncMap{
+	"quote":  func(s string) string { return fmt.Sprintf("%q", s) },
+	"params": func(names []string) string { return strings.Join(names, ", ") },
+	"comment": func(s string) string {
+		return strings.ReplaceAll(strings.TrimSpace(s), "\n", "\n// ")
+	},
+}).Parse(`// Code generated by toolbox errgen. DO NOT EDIT.
+
+package {{ .Package }}
+
+import "github.com/rlapenok/toolbox/errors"
+
+{{- if or .Catalog.Service .Catalog.Domain }}
+
+const (
+{{- if .Catalog.Service }}
+	// Service - service that returns the errors
+	Service = {{ quote .Catalog.Service }}
+{{- end }}
+{{- if .Catalog.Domain }}
+	// Domain - default domain of the errors
+	Domain = {{ quote .Catalog.Domain }}
+{{- end }}
+)
+{{- end }}
+
+// Reasons of the errors
+const (
+{{- range .Entries }}
+	Reason{{ .ID }} errors.Reason = {{ quote .Reason }}
+{{- end }}
+)
+
+
+// Errors are built by the New constructors on every call and matched by the Is functions:
+// the builders (WithDetails, WithReason, ...) modify their receiver, so shared error values are not generated.
+{{ range .Entries }}
+// Is{{ .ID }} - check if err is the {{ .ID }} error by its code and reason
+func Is{{ .ID }}(err error) bool {
+	tbErr, ok := errors.From(err)
+	return ok && tbErr.Code() == errors.{{ .CodeName }} && tbErr.Reason() == Reason{{ .ID }}
+}
+
+// New{{ .ID }} - {{ if .Description }}{{ comment .Description }}{{ else }}{{ comment .Message }}{{ end }}
+func New{{ .ID }}({{ if .Params }}{{ params .Params }} any{{ end }}) *errors.Error {
+{{- if .Params }}
+	params := map[string]any{
+{{- range .Params }}
+		{{ quote . }}: {{ . }},
+{{- end }}
+	}
+{{ end }}
+	details := errors.NewDetails()
+{{- if $.Catalog.Service }}
+	details.WithService(Service)
+{{- end }}
+{{- if .Domain }}
+	details.WithDomain({{ if eq .Domain $.Catalog.Domain }}Domain{{ else }}{{ quote .Domain }}{{ end }})
+{{- end }}
+{{- if and .Params .Messages }}
+	details.WithParams(params)
+{{- end }}
+{{- range .Messages }}
+	details.WithLocaleMessage({{ quote .Locale }}, {{ quote .Template }})
+{{- end }}
+
+	return errors.New(errors.{{ .CodeName }}, {{ .MessageExpr }}).
+		WithReason(Reason{{ .ID }}).
+		WithDetails(details)
+}
+{{ end }}`))
+
+// goEntry - error prepared for the Go template
+type goEntry struct {
+	ID          string
+	CodeName    string
+	Reason      string
+	Domain      string
+	Message     string
+	MessageExpr string
+	Description string
+	Params      []string
+	Messages    []goMessage
+}
+
+// goMessage - locale message prepared for the Go template
+type goMessage struct {
+	Locale   string
+	Template string
+}
+
+// GenerateGo - generate the Go source with constructors of the catalog errors.
+// pkg overrides the package of the catalog.
+func GenerateGo(c *Catalog, pkg string) ([]byte, error) {
+	if pkg == "" {
+		pkg = c.Package
+	}
+	if pkg == "" {
+		return nil, errors.New(errors.InvalidParameter, "package name is required")
+	}
+
+	entries := make([]goEntry, 0, len(c.Errors))
+	for i := range c.Errors {
+		entry := &c.Errors[i]
+		params := entry.Params()
+
+		messageExpr := fmt.Sprintf("%q", entry.Message)
+		if len(params) > 0 {
+			messageExpr = fmt.Sprintf("errors.Expand(%q, params)", entry.Message)
+		}
+
+		goEntry := goEntry{
+			ID:          entry.ID,
+			CodeName:    entry.ErrorCode().String(),
+			Reason:      entry.Reason,
+			Domain:      c.DomainOf(entry),
+			Message:     entry.Message,
+			MessageExpr: messageExpr,
+			Description: entry.Description,
+			Params:      params,
+		}
+		for _, locale := range entry.Locales() {
+			goEntry.Messages = append(goEntry.Messages, goMessage{
+				Locale:   locale,
+				Template: entry.Messages[locale],
+			})
+		}
+
+		entries = append(entries, goEntry)
+	}
+
+	var buf bytes.Buffer
+	err := goTemplate.Execute(&buf, map[string]any{
+		"Package": pkg,
+		"Catalog": c,
+		"Entries": entries,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.Internal, "failed to render Go source")
+	}
+
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, errors.Internal, "failed to format Go source")
+	}
+
+	return source, nil
+}
+
+// GenerateMarkdown - generate the Markdown reference of the catalog errors
+func GenerateMarkdown(c *Catalog) []byte {
+	var b bytes.Buffer
+
+	title := "Errors"
+	if c.Service != "" {
+		title = "Errors of " + c.Service
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "<!-- Code generated by toolbox errgen. DO NOT EDIT. -->\n\n")
+
+	b.WriteString("| ID | Code | HTTP | GRPC | Reason | Domain | Message |\n")
+	b.WriteString("|----|------|------|------|--------|--------|---------|\n")
+	for i := range c.Errors {
+		entry := &c.Errors[i]
+		code := entry.ErrorCode()
+		fmt.Fprintf(&b, "| [%s](#%s) | %s | %d | %s | `%s` | %s | %s |\n",
+			entry.ID, strings.ToLower(entry.ID), code, code.HTTPStatus(), code.GRPCCode(),
+			entry.Reason, markdownCell(c.DomainOf(entry)), markdownCell(entry.Message))
+	}
+
+	for i := range c.Errors {
+		entry := &c.Errors[i]
+		code := entry.ErrorCode()
+
+		fmt.Fprintf(&b, "\n## %s\n\n", entry.ID)
+		if entry.Description != "" {
+			fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(entry.Description))
+		}
+		fmt.Fprintf(&b, "- Code: `%s` (HTTP %d %s, GRPC %s)\n", code, code.HTTPStatus(), http.StatusText(code.HTTPStatus()), code.GRPCCode())
+		fmt.Fprintf(&b, "- Reason: `%s`\n", entry.Reason)
+		if domain := c.DomainOf(entry); domain != "" {
+			fmt.Fprintf(&b, "- Domain: `%s`\n", domain)
+		}
+		fmt.Fprintf(&b, "- Message: %s\n", entry.Message)
+		if params := entry.Params(); len(params) > 0 {
+			fmt.Fprintf(&b, "- Parameters: `%s`\n", strings.Join(params, "`, `"))
+		}
+
+		if locales := entry.Locales(); len(locales) > 0 {
+			b.WriteString("\n| Locale | Message |\n|--------|---------|\n")
+			for _, locale := range locales {
+				fmt.Fprintf(&b, "| %s | %s |\n", locale, markdownCell(entry.Messages[locale]))
+			}
+		}
+	}
+
+	return b.Bytes()
+}
+
+// markdownCell - escape the text for a Markdown table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
@@ -1,6 +1,9 @@
 package errors
 
 import (
+	"net/http"
+	"strconv"
+
 	"google.golang.org/grpc/codes"
 )
 
@@ -10,15 +13,27 @@ type Code int
 // Error codes
 const (
 	//Codes for other
-	InvalidParameter Code = 1
+	InvalidParameter Code = 1 // http.BadRequest / grpc.InvalidArgument
+
+	//Codes without their own HTTP status
+	Aborted            Code = 1001 // http.Conflict / grpc.Aborted
+	OutOfRange         Code = 1002 // http.BadRequest / grpc.OutOfRange
+	FailedPrecondition Code = 1003 // http.BadRequest / grpc.FailedPrecondition
+	DataLoss           Code = 1004 // http.InternalServerError / grpc.DataLoss
+	AlreadyExists      Code = 1005 // http.Conflict / grpc.AlreadyExists
 
 	//Codes for web
-	BadRequest      Code = 400 // http.BadRequest / grpc.InvalidArgument
-	Unauthorized    Code = 401 // http.Unauthorized / grpc.Unauthenticated
-	Forbidden       Code = 403 // http.Forbidden / grpc.PermissionDenied
-	NotFound        Code = 404 // http.NotFound / grpc.NotFound
-	Conflict        Code = 409 // http.Conflict / grpc.AlreadyExists
-	TooManyRequests Code = 429 // http.TooManyRequests / grpc.ResourceExhausted
+	BadRequest          Code = 400 // http.BadRequest / grpc.InvalidArgument
+	Unauthorized        Code = 401 // http.Unauthorized / grpc.Unauthenticated
+	PaymentRequired     Code = 402 // http.PaymentRequired / grpc.FailedPrecondition
+	Forbidden           Code = 403 // http.Forbidden / grpc.PermissionDenied
+	NotFound            Code = 404 // http.NotFound / grpc.NotFound
+	Conflict            Code = 409 // http.Conflict / grpc.AlreadyExists
+	Gone                Code = 410 // http.Gone / grpc.NotFound
+	PreconditionFailed  Code = 412 // http.PreconditionFailed / grpc.FailedPrecondition
+	UnprocessableEntity Code = 422 // http.UnprocessableEntity / grpc.InvalidArgument
+	TooManyRequests     Code = 429 // http.TooManyRequests / grpc.ResourceExhausted
+	Canceled            Code = 499 // client closed request (nginx) / grpc.Canceled
 
 	Internal       Code = 500 // http.InternalServerError / grpc.Internal
 	NotImplemented Code = 501 // http.NotImplemented / grpc.Unimplemented
@@ -27,36 +42,187 @@ const (
 	GatewayTimeout Code = 504 // http.GatewayTimeout / grpc.DeadlineExceeded
 )
 
+// codeNames - names of the error codes as declared in this package
+var codeNames = map[Code]string{
+	InvalidParameter: "InvalidParameter",
+
+	Aborted:            "Aborted",
+	OutOfRange:         "OutOfRange",
+	FailedPrecondition: "FailedPrecondition",
+	DataLoss:           "DataLoss",
+	AlreadyExists:      "AlreadyExists",
+
+	BadRequest:          "BadRequest",
+	Unauthorized:        "Unauthorized",
+	PaymentRequired:     "PaymentRequired",
+	Forbidden:           "Forbidden",
+	NotFound:            "NotFound",
+	Conflict:            "Conflict",
+	Gone:                "Gone",
+	PreconditionFailed:  "PreconditionFailed",
+	UnprocessableEntity: "UnprocessableEntity",
+	TooManyRequests:     "TooManyRequests",
+	Canceled:            "Canceled",
+
+	Internal:       "Internal",
+	NotImplemented: "NotImplemented",
+	BadGateway:     "BadGateway",
+	Unavailable:    "Unavailable",
+	GatewayTimeout: "GatewayTimeout",
+}
+
 // toHTTPStatus returns the HTTP status code for the error code
 func (c Code) toHTTPStatus() int {
-	return int(c)
+	switch c {
+	case InvalidParameter, OutOfRange, FailedPrecondition:
+		return http.StatusBadRequest
+	case Aborted, AlreadyExists:
+		return http.StatusConflict
+	case DataLoss:
+		return http.StatusInternalServerError
+	}
+
+	if c >= 400 && c <= 599 {
+		return int(c)
+	}
+
+	return http.StatusInternalServerError
+}
+
+// retryable reports whether errors with the code are retryable by default
+func (c Code) retryable() bool {
+	switch c {
+	case Aborted, TooManyRequests, BadGateway, Unavailable, GatewayTimeout:
+		return true
+	default:
+		return false
+	}
 }
 
 // toGRPCCode returns the GRPC code for the error code
 func (c Code) toGRPCCode() codes.Code {
 	switch c {
-	case 400:
+	case InvalidParameter, BadRequest, UnprocessableEntity:
 		return codes.InvalidArgument
-	case 401:
+	case Unauthorized:
 		return codes.Unauthenticated
-	case 403:
+	case PaymentRequired, PreconditionFailed, FailedPrecondition:
+		return codes.FailedPrecondition
+	case Forbidden:
 		return codes.PermissionDenied
-	case 404:
+	case NotFound, Gone:
 		return codes.NotFound
-	case 409:
+	case Conflict, AlreadyExists:
 		return codes.AlreadyExists
-	case 429:
+	case Aborted:
+		return codes.Aborted
+	case OutOfRange:
+		return codes.OutOfRange
+	case TooManyRequests:
 		return codes.ResourceExhausted
+	case Canceled:
+		return codes.Canceled
 
-	case 500:
+	case Internal:
 		return codes.Internal
-	case 501:
+	case DataLoss:
+		return codes.DataLoss
+	case NotImplemented:
 		return codes.Unimplemented
-	case 502, 503:
+	case BadGateway, Unavailable:
 		return codes.Unavailable
-	case 504:
+	case GatewayTimeout:
 		return codes.DeadlineExceeded
 	default:
 		return codes.Unknown
 	}
 }
+
+// FromGRPCCode returns the error code for the GRPC code.
+// OK and unknown codes are mapped to Internal.
+func FromGRPCCode(c codes.Code) Code {
+	switch c {
+	case codes.Canceled:
+		return Canceled
+	case codes.InvalidArgument:
+		return BadRequest
+	case codes.DeadlineExceeded:
+		return GatewayTimeout
+	case codes.NotFound:
+		return NotFound
+	case codes.AlreadyExists:
+		return Conflict
+	case codes.PermissionDenied:
+		return Forbidden
+	case codes.ResourceExhausted:
+		return TooManyRequests
+	case codes.FailedPrecondition:
+		return FailedPrecondition
+	case codes.Aborted:
+		return Aborted
+	case codes.OutOfRange:
+		return OutOfRange
+	case codes.Unimplemented:
+		return NotImplemented
+	case codes.Unavailable:
+		return Unavailable
+	case codes.DataLoss:
+		return DataLoss
+	case codes.Unauthenticated:
+		return Unauthorized
+	default:
+		return Internal
+	}
+}
+
+// FromHTTPStatus returns the error code for the HTTP status.
+// Statuses without their own code are mapped to BadRequest (4xx) or Internal (other).
+func FromHTTPStatus(status int) Code {
+	if status >= 400 && status <= 599 {
+		if _, ok := codeNames[Code(status)]; ok {
+			return Code(status)
+		}
+	}
+
+	if status >= 400 && status < 500 {
+		return BadRequest
+	}
+
+	return Internal
+}
+
+// String returns the name of the error code
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+
+	return "Code(" + strconv.Itoa(int(c)) + ")"
+}
+
+// ParseCode returns the error code by its name (e.g. "NotFound") or number (e.g. "404")
+func ParseCode(s string) (Code, bool) {
+	for code, name := range codeNames {
+		if name == s {
+			return code, true
+		}
+	}
+
+	if n, err := strconv.Atoi(s); err == nil {
+		if _, ok := codeNames[Code(n)]; ok {
+			return Code(n), true
+		}
+	}
+
+	return 0, false
+}
+
+// HTTPStatus returns the HTTP status code for the error code
+func (c Code) HTTPStatus() int {
+	return c.toHTTPStatus()
+}
+
+// GRPCCode returns the GRPC code for the error code
+func (c Code) GRPCCode() codes.Code {
+	return c.toGRPCCode()
+}
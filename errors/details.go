@@ -1,10 +1,58 @@
 package errors
 
+import "sort"
+
 // Details is a struct that contains the details of the error
 type Details struct {
-	service        string
-	domain         string
-	localeMessages map[string]string
+	service         string
+	domain          string
+	localeMessages  map[string]string
+	params          map[string]any
+	fieldViolations []FieldViolation
+}
+
+// FieldViolation describes a single invalid field of the request
+type FieldViolation struct {
+	// path to the field, e.g. "address.city"
+	Field string
+	// validation rule that failed, e.g. "required" or "min"
+	Rule string
+	// parameter of the rule, e.g. "3" for "min=3"
+	Param string
+	// description of why the field is invalid
+	Description string
+	// descriptions of why the field is invalid keyed by locale
+	localeDescriptions map[string]string
+}
+
+// NewFieldViolation creates a new FieldViolation struct
+func NewFieldViolation(field string, description string) *FieldViolation {
+	return &FieldViolation{
+		Field:              field,
+		Description:        description,
+		localeDescriptions: make(map[string]string),
+	}
+}
+
+// WithRule adds the failed rule and its parameter to the FieldViolation struct
+func (v *FieldViolation) WithRule(rule string, param string) *FieldViolation {
+	v.Rule = rule
+	v.Param = param
+	return v
+}
+
+// WithLocaleDescription adds a locale description to the FieldViolation struct
+func (v *FieldViolation) WithLocaleDescription(locale string, description string) *FieldViolation {
+	if v.localeDescriptions == nil {
+		v.localeDescriptions = make(map[string]string)
+	}
+	v.localeDescriptions[locale] = description
+	return v
+}
+
+// LocaleDescription returns the locale description for the given locale
+func (v *FieldViolation) LocaleDescription(locale string) string {
+	return v.localeDescriptions[locale]
 }
 
 // NewDetails creates a new Details struct
@@ -32,7 +80,70 @@ func (d *Details) WithLocaleMessage(locale string, message string) *Details {
 	return d
 }
 
-// LocaleMessage returns the locale message for the given locale
+// WithParam adds a named parameter of the locale message templates to the Details struct
+func (d *Details) WithParam(name string, value any) *Details {
+	if d.params == nil {
+		d.params = make(map[string]any)
+	}
+	d.params[name] = value
+	return d
+}
+
+// WithParams adds named parameters of the locale message templates to the Details struct
+func (d *Details) WithParams(params map[string]any) *Details {
+	for name, value := range params {
+		d.WithParam(name, value)
+	}
+	return d
+}
+
+// WithFieldViolation adds a field violation to the Details struct
+func (d *Details) WithFieldViolation(field string, description string) *Details {
+	return d.WithViolation(NewFieldViolation(field, description))
+}
+
+// WithViolation adds a prepared field violation to the Details struct
+func (d *Details) WithViolation(violation *FieldViolation) *Details {
+	d.fieldViolations = append(d.fieldViolations, *violation)
+	return d
+}
+
+// Service returns the service of the Details struct
+func (d *Details) Service() string {
+	return d.service
+}
+
+// Domain returns the domain of the Details struct
+func (d *Details) Domain() string {
+	return d.domain
+}
+
+// LocaleMessage returns the locale message for the given locale with the parameters expanded
 func (d *Details) LocaleMessage(locale string) string {
-	return d.localeMessages[locale]
+	return Expand(d.localeMessages[locale], d.params)
+}
+
+// LocaleMessages returns the locale messages keyed by locale with the parameters expanded
+func (d *Details) LocaleMessages() map[string]string {
+	messages := make(map[string]string, len(d.localeMessages))
+	for locale := range d.localeMessages {
+		messages[locale] = d.LocaleMessage(locale)
+	}
+	return messages
+}
+
+// Locales returns the sorted list of locales that have a message
+func (d *Details) Locales() []string {
+	locales := make([]string, 0, len(d.localeMessages))
+	for locale := range d.localeMessages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	return locales
+}
+
+// FieldViolations returns the field violations of the Details struct
+func (d *Details) FieldViolations() []FieldViolation {
+	return d.fieldViolations
 }
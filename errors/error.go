@@ -2,6 +2,10 @@ package errors
 
 import (
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc/codes"
 )
@@ -19,20 +23,99 @@ type Error struct {
 
 	// any details to be returned to the client
 	details any
+
+	// any details to be logged only, never returned to the client
+	internalDetails any
+
+	// underlying error
+	cause error
+
+	// call stack where the error was created
+	stack Stack
+
+	// whether the operation may be retried, nil means the default of the code
+	retryable *bool
+
+	// how long to wait before retrying
+	retryAfter time.Duration
+
+	// GRPC code overriding the mapping of the error code
+	grpcCode *codes.Code
 }
 
 // implement the error interface
 func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s, cause: %v", e.text(), e.cause)
+	}
+
+	return e.text()
+}
+
+// text returns the error description without the cause
+func (e *Error) text() string {
+	if e.internalDetails != nil {
+		return fmt.Sprintf("code: %d, message: %s, reason: %s, details: %v, internal details: %v", e.errCode, e.message, e.reason, e.details, e.internalDetails)
+	}
+
 	return fmt.Sprintf("code: %d, message: %s, reason: %s, details: %v", e.errCode, e.message, e.reason, e.details)
 }
 
-// New creates a new error with the given code and message
-func New(code Code, message string) *Error {
-	return &Error{
+// Format implements fmt.Formatter, %+v prints the call stack and the cause chain
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			io.WriteString(s, e.text())
+			if len(e.stack) > 0 {
+				io.WriteString(s, "\n")
+				io.WriteString(s, e.stack.String())
+			}
+			if e.cause != nil {
+				fmt.Fprintf(s, "\ncaused by: %+v", e.cause)
+			}
+			return
+		}
+		io.WriteString(s, e.Error())
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		fmt.Fprintf(s, "%%!%c(*errors.Error=%s)", verb, e.Error())
+	}
+}
+
+// newError creates a new error, capturing the stack of the caller of its caller
+func newError(cause error, code Code, message string) *Error {
+	err := &Error{
 		errCode: code,
 		message: message,
 		details: nil,
+		cause:   cause,
 	}
+
+	// skip runtime.Callers, callers, newError and the exported constructor
+	if captureStack.Load() {
+		err.stack = callers(4)
+	}
+
+	return err
+}
+
+// New creates a new error with the given code and message
+func New(code Code, message string) *Error {
+	return newError(nil, code, message)
+}
+
+// Wrap creates a new error with the given code and message that wraps err
+func Wrap(err error, code Code, message string) *Error {
+	return newError(err, code, message)
+}
+
+// Wrapf creates a new error with the given code and formatted message that wraps err
+func Wrapf(err error, code Code, format string, args ...any) *Error {
+	return newError(err, code, fmt.Sprintf(format, args...))
 }
 
 // add details to the error
@@ -42,6 +125,13 @@ func (e *Error) WithDetails(details any) *Error {
 	return e
 }
 
+// add internal details to the error, they are logged but never returned to the client
+func (e *Error) WithInternalDetails(details any) *Error {
+	e.internalDetails = details
+
+	return e
+}
+
 // add reason to the error
 func (e *Error) WithReason(reason Reason) *Error {
 	e.reason = reason
@@ -49,13 +139,52 @@ func (e *Error) WithReason(reason Reason) *Error {
 	return e
 }
 
+// override the GRPC code of the error
+func (e *Error) WithGRPCCode(code codes.Code) *Error {
+	e.grpcCode = &code
+
+	return e
+}
+
+// mark the error as retryable or not, overriding the default of the code
+func (e *Error) WithRetryable(retryable bool) *Error {
+	e.retryable = &retryable
+
+	return e
+}
+
+// add the delay before retrying to the error, the error becomes retryable
+func (e *Error) WithRetryAfter(retryAfter time.Duration) *Error {
+	e.retryAfter = retryAfter
+
+	return e.WithRetryable(true)
+}
+
+// add cause to the error
+func (e *Error) WithCause(cause error) *Error {
+	e.cause = cause
+
+	return e
+}
+
+// capture the call stack of the caller regardless of SetCaptureStack
+func (e *Error) WithStack() *Error {
+	e.stack = callers(3)
+
+	return e
+}
+
 // return the HTTP status code
 func (e *Error) ToHTTPStatus() int {
 	return e.errCode.toHTTPStatus()
 }
 
-// return the GRPC codeq
+// return the GRPC code, the override set with WithGRPCCode takes precedence
 func (e *Error) ToGRPCCode() codes.Code {
+	if e.grpcCode != nil {
+		return *e.grpcCode
+	}
+
 	return e.errCode.toGRPCCode()
 }
 
@@ -78,3 +207,96 @@ func (e *Error) Reason() Reason {
 func (e *Error) Details() any {
 	return e.details
 }
+
+// return whether the operation may be retried.
+// Unless set with WithRetryable, errors with TooManyRequests, BadGateway,
+// Unavailable and GatewayTimeout codes are retryable.
+func (e *Error) Retryable() bool {
+	if e.retryable != nil {
+		return *e.retryable
+	}
+
+	return e.errCode.retryable()
+}
+
+// return how long to wait before retrying, zero if not set
+func (e *Error) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// return the internal details of the error
+func (e *Error) InternalDetails() any {
+	return e.internalDetails
+}
+
+// Redacted returns a copy of the error that is safe to return to clients in production:
+// internal details, cause and stack are dropped and the message of server errors
+// is replaced with the HTTP status text.
+func (e *Error) Redacted() *Error {
+	redacted := *e
+	redacted.internalDetails = nil
+	redacted.cause = nil
+	redacted.stack = nil
+
+	if errs, ok := redacted.details.(Errors); ok {
+		redacted.details = errs.Redacted()
+	}
+
+	if status := e.ToHTTPStatus(); status >= http.StatusInternalServerError {
+		redacted.message = strings.ToLower(http.StatusText(status))
+		if redacted.message == "" {
+			redacted.message = e.errCode.String()
+		}
+	}
+
+	return &redacted
+}
+
+// IsProduction reports whether the environment ("production" or "prod") requires redacted errors
+func IsProduction(environment string) bool {
+	return environment == "production" || environment == "prod"
+}
+
+// return the underlying error
+func (e *Error) Cause() error {
+	return e.cause
+}
+
+// return the call stack where the error was created
+func (e *Error) Stack() Stack {
+	return e.stack
+}
+
+// return the call stack as "function (file:line)" entries, suitable for structured logs
+func (e *Error) StackTrace() []string {
+	if len(e.stack) == 0 {
+		return nil
+	}
+
+	return e.stack.Lines()
+}
+
+// Unwrap returns the underlying error, used by errors.Is and errors.As
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether the error matches target.
+// A toolbox error matches another one when their codes are equal
+// and the target reason is either empty or equal to the error reason.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	if e == t {
+		return true
+	}
+
+	if e.errCode != t.errCode {
+		return false
+	}
+
+	return t.reason == "" || e.reason == t.reason
+}
@@ -0,0 +1,324 @@
+package errors
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// metadata keys of errdetails.ErrorInfo
+const (
+	errorInfoServiceKey = "service"
+	errorInfoCodeKey    = "code"
+
+	// keys of the ErrorInfo of a sub-error of an aggregate (see Join)
+	errorInfoIndexKey         = "error_index"
+	errorInfoMessageKey       = "message"
+	errorInfoLocaleMessageKey = "message:" // followed by the locale
+)
+
+// metadata keys of the preferred locales of a GRPC call, the second one is set by grpc-gateway
+var acceptLanguageKeys = []string{"accept-language", "grpcgateway-accept-language"}
+
+// ToGRPCStatus converts the error to a GRPC status.
+// The toolbox code, reason, domain and service are sent as errdetails.ErrorInfo,
+// the locale messages as errdetails.LocalizedMessage
+// the field violations as errdetails.BadRequest and the retry delay as errdetails.RetryInfo.
+// Every sub-error of an aggregate (see Join) is sent as its own errdetails.ErrorInfo
+// with its message and locale messages in the metadata.
+func (e *Error) ToGRPCStatus() *status.Status {
+	return e.toGRPCStatus(false, nil)
+}
+
+// ToLocalizedGRPCStatus converts the error to a GRPC status like ToGRPCStatus,
+// but sends only the locale message negotiated from the preferred locales (see Details.Localize)
+func (e *Error) ToLocalizedGRPCStatus(preferred ...string) *status.Status {
+	return e.toGRPCStatus(true, preferred)
+}
+
+// toGRPCStatus converts the error to a GRPC status with all or only the negotiated locale messages
+func (e *Error) toGRPCStatus(localize bool, preferred []string) *status.Status {
+	st := status.New(e.ToGRPCCode(), e.message)
+
+	details, _ := e.details.(*Details)
+
+	messages := make([]protoadapt.MessageV1, 0)
+
+	// the toolbox code is sent to restore it on the client, the GRPC code may be overridden
+	info := &errdetails.ErrorInfo{
+		Reason: string(e.reason),
+		Metadata: map[string]string{
+			errorInfoCodeKey: strconv.Itoa(int(e.errCode)),
+		},
+	}
+	if details != nil {
+		info.Domain = details.domain
+		if details.service != "" {
+			info.Metadata[errorInfoServiceKey] = details.service
+		}
+	}
+	messages = append(messages, info)
+
+	if details != nil {
+		if localize {
+			if locale, message, ok := details.Localize(preferred...); ok {
+				messages = append(messages, &errdetails.LocalizedMessage{
+					Locale:  locale,
+					Message: message,
+				})
+			}
+		} else {
+			for _, locale := range details.Locales() {
+				messages = append(messages, &errdetails.LocalizedMessage{
+					Locale:  locale,
+					Message: details.LocaleMessage(locale),
+				})
+			}
+		}
+
+		if len(details.fieldViolations) > 0 {
+			badRequest := &errdetails.BadRequest{}
+			for _, violation := range details.fieldViolations {
+				badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+					Field:       violation.Field,
+					Description: violation.Description,
+					Reason:      violation.Rule,
+				})
+			}
+			messages = append(messages, badRequest)
+		}
+	}
+
+	if errs, ok := e.details.(Errors); ok {
+		messages = append(messages, errs.toGRPCDetails(localize, preferred)...)
+	}
+
+	if e.retryAfter > 0 {
+		messages = append(messages, &errdetails.RetryInfo{
+			RetryDelay: durationpb.New(e.retryAfter),
+		})
+	}
+
+	withDetails, err := st.WithDetails(messages...)
+	if err != nil {
+		return st
+	}
+
+	return withDetails
+}
+
+// GRPCStatus implements the interface used by status.FromError,
+// so a toolbox error returned from a GRPC handler keeps its details
+func (e *Error) GRPCStatus() *status.Status {
+	return e.ToGRPCStatus()
+}
+
+// toGRPCDetails converts the sub-errors to an errdetails.ErrorInfo each
+// and their field violations to one errdetails.BadRequest
+func (errs Errors) toGRPCDetails(localize bool, preferred []string) []protoadapt.MessageV1 {
+	messages := make([]protoadapt.MessageV1, 0, len(errs)+1)
+	badRequest := &errdetails.BadRequest{}
+
+	for i, err := range errs {
+		info := &errdetails.ErrorInfo{
+			Reason: string(err.reason),
+			Metadata: map[string]string{
+				errorInfoCodeKey:    strconv.Itoa(int(err.errCode)),
+				errorInfoIndexKey:   strconv.Itoa(i),
+				errorInfoMessageKey: err.message,
+			},
+		}
+
+		if details, ok := err.details.(*Details); ok {
+			info.Domain = details.domain
+			if details.service != "" {
+				info.Metadata[errorInfoServiceKey] = details.service
+			}
+
+			if localize {
+				if locale, message, ok := details.Localize(preferred...); ok {
+					info.Metadata[errorInfoLocaleMessageKey+locale] = message
+				}
+			} else {
+				for _, locale := range details.Locales() {
+					info.Metadata[errorInfoLocaleMessageKey+locale] = details.LocaleMessage(locale)
+				}
+			}
+
+			for _, violation := range details.fieldViolations {
+				badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+					Field:       violation.Field,
+					Description: violation.Description,
+					Reason:      violation.Rule,
+				})
+			}
+		}
+
+		messages = append(messages, info)
+	}
+
+	if len(badRequest.FieldViolations) > 0 {
+		messages = append(messages, badRequest)
+	}
+
+	return messages
+}
+
+// subErrorFromErrorInfo reconstructs a sub-error of an aggregate from its errdetails.ErrorInfo
+func subErrorFromErrorInfo(info *errdetails.ErrorInfo) *Error {
+	metadata := info.GetMetadata()
+
+	code, ok := ParseCode(metadata[errorInfoCodeKey])
+	if !ok {
+		code = Internal
+	}
+
+	err := New(code, metadata[errorInfoMessageKey]).WithReason(Reason(info.GetReason()))
+
+	details := NewDetails()
+	hasDetails := false
+	if info.GetDomain() != "" {
+		details.WithDomain(info.GetDomain())
+		hasDetails = true
+	}
+	if service := metadata[errorInfoServiceKey]; service != "" {
+		details.WithService(service)
+		hasDetails = true
+	}
+	for key, message := range metadata {
+		if locale, ok := strings.CutPrefix(key, errorInfoLocaleMessageKey); ok {
+			details.WithLocaleMessage(locale, message)
+			hasDetails = true
+		}
+	}
+	if hasDetails {
+		err.details = details
+	}
+
+	return err
+}
+
+// FromGRPCStatus reconstructs a toolbox error from a GRPC status.
+// It returns nil for a nil or OK status.
+func FromGRPCStatus(st *status.Status) *Error {
+	if st == nil || st.Code() == codes.OK {
+		return nil
+	}
+
+	err := New(FromGRPCCode(st.Code()), st.Message())
+
+	var details *Details
+	getDetails := func() *Details {
+		if details == nil {
+			details = NewDetails()
+		}
+		return details
+	}
+
+	subErrors := map[int]*Error{}
+
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			// the sub-error of an aggregate
+			if index, convErr := strconv.Atoi(d.GetMetadata()[errorInfoIndexKey]); convErr == nil {
+				subErrors[index] = subErrorFromErrorInfo(d)
+				continue
+			}
+
+			err.reason = Reason(d.GetReason())
+			if code, ok := ParseCode(d.GetMetadata()[errorInfoCodeKey]); ok {
+				err.errCode = code
+				if code.toGRPCCode() != st.Code() {
+					err.WithGRPCCode(st.Code())
+				}
+			}
+			if d.GetDomain() != "" {
+				getDetails().WithDomain(d.GetDomain())
+			}
+			if service := d.GetMetadata()[errorInfoServiceKey]; service != "" {
+				getDetails().WithService(service)
+			}
+		case *errdetails.RetryInfo:
+			err.WithRetryAfter(d.GetRetryDelay().AsDuration())
+		case *errdetails.LocalizedMessage:
+			getDetails().WithLocaleMessage(d.GetLocale(), d.GetMessage())
+		case *errdetails.BadRequest:
+			for _, violation := range d.GetFieldViolations() {
+				fieldViolation := NewFieldViolation(violation.GetField(), violation.GetDescription()).
+					WithRule(violation.GetReason(), "")
+				if localized := violation.GetLocalizedMessage(); localized != nil {
+					fieldViolation.WithLocaleDescription(localized.GetLocale(), localized.GetMessage())
+				}
+				getDetails().WithViolation(fieldViolation)
+			}
+		}
+	}
+
+	if details != nil {
+		err.details = details
+	}
+
+	// the sub-errors of an aggregate are its details
+	if len(subErrors) > 0 {
+		indexes := make([]int, 0, len(subErrors))
+		for index := range subErrors {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+
+		errs := make(Errors, 0, len(indexes))
+		for _, index := range indexes {
+			errs = append(errs, subErrors[index])
+		}
+		err.details = errs
+	}
+
+	return err
+}
+
+// FromGRPCError reconstructs a toolbox error from an error returned by a GRPC client.
+// Errors that do not carry a GRPC status are wrapped as Internal.
+func FromGRPCError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return Wrap(err, Internal, err.Error())
+	}
+
+	tbErr := FromGRPCStatus(st)
+	if tbErr != nil {
+		tbErr.cause = err
+	}
+
+	return tbErr
+}
+
+// AcceptLanguageFromIncomingContext returns the preferred locales sent by the GRPC client
+// in the "accept-language" metadata, ordered by preference
+func AcceptLanguageFromIncomingContext(ctx context.Context) []string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var locales []string
+	for _, key := range acceptLanguageKeys {
+		for _, value := range md.Get(key) {
+			locales = append(locales, ParseAcceptLanguage(value)...)
+		}
+	}
+
+	return locales
+}
@@ -0,0 +1,170 @@
+package errors
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxErrorBodySize is the maximum size of an error body read by FromHTTPResponse
+	maxErrorBodySize = 1 << 20
+
+	// media type of RFC 9457 problem details
+	problemJSONMediaType = "application/problem+json"
+)
+
+// problemJSON is the wire representation of RFC 9457 problem details
+// with the toolbox members as extensions
+type problemJSON struct {
+	Type       string          `json:"type"`
+	Title      string          `json:"title"`
+	Status     int             `json:"status"`
+	Detail     string          `json:"detail"`
+	Code       Code            `json:"code"`
+	Reason     Reason          `json:"reason"`
+	Retryable  *bool           `json:"retryable"`
+	RetryAfter float64         `json:"retry_after"`
+	Details    json.RawMessage `json:"details"`
+}
+
+// FromHTTPResponse reconstructs a toolbox error from the error response of another toolbox service.
+// Both the toolbox JSON body and RFC 9457 problem details are understood, other bodies
+// are mapped by the HTTP status. It returns nil for responses with a status below 400.
+// The body is consumed but not closed.
+func FromHTTPResponse(resp *http.Response) *Error {
+	if resp == nil || resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	var body []byte
+	if resp.Body != nil {
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		if err != nil {
+			return Wrap(err, FromHTTPStatus(resp.StatusCode), http.StatusText(resp.StatusCode))
+		}
+		body = data
+	}
+
+	err := fromBody(resp, body)
+
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok && err.retryAfter == 0 {
+		err.WithRetryAfter(retryAfter)
+	}
+
+	return err
+}
+
+// fromBody decodes the error body by its content type
+func fromBody(resp *http.Response, body []byte) *Error {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	switch {
+	case mediaType == problemJSONMediaType:
+		if err, ok := fromProblemBody(body, resp.StatusCode); ok {
+			return err
+		}
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		if err, ok := fromToolboxBody(body); ok {
+			return err
+		}
+	}
+
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+
+	return New(FromHTTPStatus(resp.StatusCode), message)
+}
+
+// parseRetryAfter parses the Retry-After header in seconds or as an HTTP date
+func parseRetryAfter(header string) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// RetryAfterHeader formats the delay as the value of the Retry-After header in whole seconds
+func RetryAfterHeader(retryAfter time.Duration) string {
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+
+	return strconv.FormatInt(seconds, 10)
+}
+
+// fromToolboxBody decodes the toolbox JSON error body
+func fromToolboxBody(body []byte) (*Error, bool) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return nil, false
+	}
+	if _, ok := probe["code"]; !ok {
+		return nil, false
+	}
+	if _, ok := probe["message"]; !ok {
+		return nil, false
+	}
+
+	err := New(0, "")
+	if json.Unmarshal(body, err) != nil {
+		return nil, false
+	}
+
+	return err, true
+}
+
+// fromProblemBody decodes RFC 9457 problem details
+func fromProblemBody(body []byte, status int) (*Error, bool) {
+	var problem problemJSON
+	if err := json.Unmarshal(body, &problem); err != nil {
+		return nil, false
+	}
+
+	if problem.Status != 0 {
+		status = problem.Status
+	}
+
+	code := problem.Code
+	if code == 0 {
+		code = FromHTTPStatus(status)
+	}
+
+	message := problem.Detail
+	if message == "" {
+		message = problem.Title
+	}
+
+	details, err := unmarshalDetails(problem.Details)
+	if err != nil {
+		return nil, false
+	}
+
+	tbErr := New(code, message).
+		WithReason(problem.Reason).
+		WithDetails(details)
+	tbErr.retryable = problem.Retryable
+	tbErr.retryAfter = secondsToDuration(problem.RetryAfter)
+
+	return tbErr, true
+}
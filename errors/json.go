@@ -0,0 +1,201 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Wire schema of a toolbox error, shared by the HTTP error handler and the JSON encoding:
+//
+//	{
+//	  "code": 404,                          // toolbox Code, equals the HTTP status for web codes
+//	  "message": "user not found",          // developer message
+//	  "reason": "user_not_found",           // machine readable Reason, may be empty
+//	  "retryable": false,                   // whether the operation may be retried
+//	  "retry_after": 1.5,                   // seconds to wait before retrying, omitted if not set
+//	  "details": {                          // *Details, any other JSON value or null
+//	    "service": "users",
+//	    "domain": "users.example.com",
+//	    "locale_messages": {"en": "user 42 not found"},
+//	    "field_violations": [{
+//	      "field": "address.city",
+//	      "rule": "required",
+//	      "param": "",
+//	      "description": "address.city is required",
+//	      "locale_descriptions": {"en": "address.city is required"}
+//	    }]
+//	  }
+//	}
+//
+// Empty members of details are omitted. Locale messages are sent with their parameters expanded.
+
+// errorJSON is the wire representation of Error
+type errorJSON struct {
+	Code       Code            `json:"code"`
+	Message    string          `json:"message"`
+	Reason     Reason          `json:"reason"`
+	Retryable  *bool           `json:"retryable,omitempty"`
+	RetryAfter float64         `json:"retry_after,omitempty"`
+	Details    json.RawMessage `json:"details"`
+}
+
+// detailsJSON is the wire representation of Details
+type detailsJSON struct {
+	Service         string               `json:"service,omitempty"`
+	Domain          string               `json:"domain,omitempty"`
+	LocaleMessages  map[string]string    `json:"locale_messages,omitempty"`
+	FieldViolations []fieldViolationJSON `json:"field_violations,omitempty"`
+}
+
+// detailsKeys are the members of detailsJSON
+var detailsKeys = map[string]bool{
+	"service":          true,
+	"domain":           true,
+	"locale_messages":  true,
+	"field_violations": true,
+}
+
+// fieldViolationJSON is the wire representation of FieldViolation
+type fieldViolationJSON struct {
+	Field              string            `json:"field"`
+	Rule               string            `json:"rule,omitempty"`
+	Param              string            `json:"param,omitempty"`
+	Description        string            `json:"description,omitempty"`
+	LocaleDescriptions map[string]string `json:"locale_descriptions,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (e *Error) MarshalJSON() ([]byte, error) {
+	details, err := json.Marshal(e.details)
+	if err != nil {
+		return nil, err
+	}
+
+	retryable := e.Retryable()
+
+	return json.Marshal(errorJSON{
+		Code:       e.errCode,
+		Message:    e.message,
+		Reason:     e.reason,
+		Retryable:  &retryable,
+		RetryAfter: e.retryAfter.Seconds(),
+		Details:    details,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// Details that match the Details schema are decoded as *Details, any other value is decoded as is.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var wire errorJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	details, err := unmarshalDetails(wire.Details)
+	if err != nil {
+		return err
+	}
+
+	e.errCode = wire.Code
+	e.message = wire.Message
+	e.reason = wire.Reason
+	e.details = details
+	e.retryable = wire.Retryable
+	e.retryAfter = secondsToDuration(wire.RetryAfter)
+
+	return nil
+}
+
+// unmarshalDetails decodes the details of an error
+func unmarshalDetails(data json.RawMessage) (any, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err == nil && isDetailsObject(object) {
+		details := NewDetails()
+		if err := details.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		return details, nil
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// isDetailsObject reports whether all members of the object belong to the Details schema
+func isDetailsObject(object map[string]json.RawMessage) bool {
+	for key := range object {
+		if !detailsKeys[key] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MarshalJSON implements json.Marshaler
+func (d *Details) MarshalJSON() ([]byte, error) {
+	wire := detailsJSON{
+		Service:        d.service,
+		Domain:         d.domain,
+		LocaleMessages: d.LocaleMessages(),
+	}
+
+	for _, violation := range d.fieldViolations {
+		wire.FieldViolations = append(wire.FieldViolations, fieldViolationJSON{
+			Field:              violation.Field,
+			Rule:               violation.Rule,
+			Param:              violation.Param,
+			Description:        violation.Description,
+			LocaleDescriptions: violation.localeDescriptions,
+		})
+	}
+
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Details) UnmarshalJSON(data []byte) error {
+	var wire detailsJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	d.service = wire.Service
+	d.domain = wire.Domain
+	d.localeMessages = make(map[string]string, len(wire.LocaleMessages))
+	for locale, message := range wire.LocaleMessages {
+		d.localeMessages[locale] = message
+	}
+	d.params = nil
+
+	d.fieldViolations = nil
+	for _, violation := range wire.FieldViolations {
+		fieldViolation := NewFieldViolation(violation.Field, violation.Description).
+			WithRule(violation.Rule, violation.Param)
+		for locale, description := range violation.LocaleDescriptions {
+			fieldViolation.WithLocaleDescription(locale, description)
+		}
+		d.fieldViolations = append(d.fieldViolations, *fieldViolation)
+	}
+
+	return nil
+}
+
+// secondsToDuration converts seconds of the wire schema to a duration
+func secondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
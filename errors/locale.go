@@ -0,0 +1,140 @@
+package errors
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// defaultLocale is the last locale of every fallback chain
+var defaultLocale atomic.Value
+
+func init() {
+	defaultLocale.Store("en")
+}
+
+// SetDefaultLocale sets the locale used when none of the preferred locales has a message
+func SetDefaultLocale(locale string) {
+	defaultLocale.Store(locale)
+}
+
+// DefaultLocale returns the locale used when none of the preferred locales has a message
+func DefaultLocale() string {
+	return defaultLocale.Load().(string)
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header ordered by preference.
+// Locales with q=0 and the "*" wildcard are skipped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var items []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		locale := strings.TrimSpace(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(name) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		items = append(items, weighted{locale: locale, q: q})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].q > items[j].q
+	})
+
+	locales := make([]string, 0, len(items))
+	for _, item := range items {
+		locales = append(locales, item.locale)
+	}
+
+	return locales
+}
+
+// Localize selects the message for the first preferred locale that has one.
+// Every preferred locale falls back to its parents (ru-RU -> ru) and then to any
+// locale of the same language (ru -> ru-RU), the default locale is tried last.
+// It returns the selected locale and the message with the parameters expanded.
+func (d *Details) Localize(preferred ...string) (string, string, bool) {
+	if d == nil || len(d.localeMessages) == 0 {
+		return "", "", false
+	}
+
+	candidates := append(append([]string{}, preferred...), DefaultLocale())
+	for _, candidate := range candidates {
+		if locale, ok := d.matchLocale(candidate); ok {
+			return locale, d.LocaleMessage(locale), true
+		}
+	}
+
+	return "", "", false
+}
+
+// matchLocale returns the locale of the Details struct that matches the tag
+func (d *Details) matchLocale(tag string) (string, bool) {
+	tag = normalizeLocale(tag)
+	if tag == "" {
+		return "", false
+	}
+
+	locales := d.Locales()
+
+	// exact match and parent tags: ru-RU -> ru
+	for chain := tag; chain != ""; chain = parentLocale(chain) {
+		for _, locale := range locales {
+			if normalizeLocale(locale) == chain {
+				return locale, true
+			}
+		}
+	}
+
+	// same language: ru -> ru-RU
+	language := baseLocale(tag)
+	for _, locale := range locales {
+		if baseLocale(normalizeLocale(locale)) == language {
+			return locale, true
+		}
+	}
+
+	return "", false
+}
+
+// normalizeLocale lowercases the tag and uses "-" as the separator
+func normalizeLocale(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+// parentLocale returns the tag without its last subtag
+func parentLocale(tag string) string {
+	if i := strings.LastIndexByte(tag, '-'); i > 0 {
+		return tag[:i]
+	}
+
+	return ""
+}
+
+// baseLocale returns the language subtag of the tag
+func baseLocale(tag string) string {
+	if i := strings.IndexByte(tag, '-'); i > 0 {
+		return tag[:i]
+	}
+
+	return tag
+}
@@ -0,0 +1,160 @@
+package errors
+
+import "strings"
+
+// Errors is a list of toolbox errors, used as the details of an aggregated error
+type Errors []*Error
+
+// Redacted returns a copy of the list with every error redacted (see Error.Redacted)
+func (errs Errors) Redacted() Errors {
+	redacted := make(Errors, 0, len(errs))
+	for _, err := range errs {
+		redacted = append(redacted, err.Redacted())
+	}
+
+	return redacted
+}
+
+// multiError is the cause of an aggregated error,
+// it exposes every sub-error to errors.Is and errors.As
+type multiError []error
+
+// implement the error interface
+func (m multiError) Error() string {
+	messages := make([]string, 0, len(m))
+	for _, err := range m {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the sub-errors, used by errors.Is and errors.As
+func (m multiError) Unwrap() []error {
+	return m
+}
+
+// severities - precedence of the codes when errors are aggregated, the higher the more severe.
+// Server errors take precedence over client errors.
+var severities = map[Code]int{
+	DataLoss:       100,
+	Internal:       99,
+	NotImplemented: 98,
+	Unavailable:    97,
+	BadGateway:     96,
+	GatewayTimeout: 95,
+
+	Unauthorized:        60,
+	Forbidden:           59,
+	PaymentRequired:     58,
+	TooManyRequests:     57,
+	Conflict:            56,
+	AlreadyExists:       55,
+	Aborted:             54,
+	PreconditionFailed:  53,
+	FailedPrecondition:  52,
+	Gone:                51,
+	NotFound:            50,
+	UnprocessableEntity: 49,
+	OutOfRange:          48,
+	BadRequest:          47,
+	InvalidParameter:    46,
+	Canceled:            45,
+}
+
+// severity returns the precedence of the code when errors are aggregated
+func (c Code) severity() int {
+	return severities[c]
+}
+
+// Join aggregates the errors into a single toolbox error.
+// Nil errors are skipped, nil is returned if no errors remain and a single
+// error is returned as is (wrapped as Internal if it is not a toolbox error).
+// The code of the aggregate is the most severe code of the errors,
+// its details are the sub-errors as Errors and errors.Is and errors.As traverse all of them.
+func Join(errs ...error) *Error {
+	causes := make(multiError, 0, len(errs))
+	for _, err := range errs {
+		if isNil(err) {
+			continue
+		}
+
+		// flatten nested aggregates
+		if tbErr, ok := err.(*Error); ok {
+			if nested, ok := tbErr.cause.(multiError); ok && tbErr.isAggregate() {
+				causes = append(causes, nested...)
+				continue
+			}
+		}
+
+		causes = append(causes, err)
+	}
+
+	switch len(causes) {
+	case 0:
+		return nil
+	case 1:
+		return toToolbox(causes[0])
+	}
+
+	subErrors := make(Errors, 0, len(causes))
+	messages := make([]string, 0, len(causes))
+	code := Code(0)
+
+	for _, cause := range causes {
+		subError := toToolbox(cause)
+		subErrors = append(subErrors, subError)
+		messages = append(messages, subError.message)
+
+		if code == 0 || subError.errCode.severity() > code.severity() {
+			code = subError.errCode
+		}
+	}
+
+	return Wrap(causes, code, strings.Join(messages, "; ")).
+		WithDetails(subErrors)
+}
+
+// Append adds the errors to err, which may be nil or an aggregate created by Join
+func Append(err error, errs ...error) *Error {
+	return Join(append([]error{err}, errs...)...)
+}
+
+// return the sub-errors of an aggregate created by Join, nil for other errors
+func (e *Error) Errors() Errors {
+	if !e.isAggregate() {
+		return nil
+	}
+
+	errs, _ := e.details.(Errors)
+
+	return errs
+}
+
+// isAggregate reports whether the error was created by Join
+func (e *Error) isAggregate() bool {
+	_, ok := e.cause.(multiError)
+	return ok
+}
+
+// toToolbox returns the toolbox error of err, wrapping other errors as Internal
+// with a generic message, their text is kept in the cause and the internal details
+func toToolbox(err error) *Error {
+	if tbErr, ok := err.(*Error); ok {
+		return tbErr
+	}
+
+	return Wrap(err, Internal, "internal server error").
+		WithInternalDetails(map[string]any{"error": err.Error()})
+}
+
+// isNil reports whether err is nil, including a nil *Error stored in the interface
+func isNil(err error) bool {
+	if err == nil {
+		return true
+	}
+
+	tbErr, ok := err.(*Error)
+
+	return ok && tbErr == nil
+}
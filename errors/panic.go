@@ -0,0 +1,34 @@
+package errors
+
+import "fmt"
+
+// FromPanic converts a recovered panic value into an Internal toolbox error.
+// The panic value is kept in the internal details, the stack of the panic is always captured
+// and a panic with an error value wraps that error.
+func FromPanic(r any) *Error {
+	err := &Error{
+		errCode: Internal,
+		message: "internal server error",
+		reason:  ReasonInternal,
+		internalDetails: map[string]any{
+			"panic": fmt.Sprint(r),
+		},
+	}
+
+	if cause, ok := r.(error); ok {
+		err.cause = cause
+	}
+
+	// skip runtime.Callers, callers and FromPanic
+	err.stack = callers(3)
+
+	return err
+}
+
+// Recover recovers a panic into *errp as a toolbox error (see FromPanic).
+// It must be deferred directly: defer errors.Recover(&err)
+func Recover(errp *error) {
+	if r := recover(); r != nil {
+		*errp = FromPanic(r)
+	}
+}
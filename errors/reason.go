@@ -12,4 +12,17 @@ const (
 	ReasonConflict        Reason = "conflict"
 	ReasonGatewayTimeout  Reason = "gateway_timeout"
 	ReasonUnavailable     Reason = "unavailable"
+
+	ReasonCanceled            Reason = "canceled"
+	ReasonPaymentRequired     Reason = "payment_required"
+	ReasonGone                Reason = "gone"
+	ReasonPreconditionFailed  Reason = "precondition_failed"
+	ReasonUnprocessableEntity Reason = "unprocessable_entity"
+	ReasonAborted             Reason = "aborted"
+	ReasonAlreadyExists       Reason = "already_exists"
+	ReasonOutOfRange          Reason = "out_of_range"
+	ReasonFailedPrecondition  Reason = "failed_precondition"
+	ReasonDataLoss            Reason = "data_loss"
+	ReasonNotImplemented      Reason = "not_implemented"
+	ReasonBadGateway          Reason = "bad_gateway"
 )
@@ -0,0 +1,82 @@
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// maxStackDepth is the maximum number of frames captured for an error
+const maxStackDepth = 32
+
+// captureStack controls whether New and Wrap capture the call stack
+var captureStack atomic.Bool
+
+// SetCaptureStack enables or disables call stack capture in New and Wrap.
+// Capture is disabled by default to keep error creation cheap.
+func SetCaptureStack(enabled bool) {
+	captureStack.Store(enabled)
+}
+
+// CaptureStackEnabled reports whether call stack capture is enabled
+func CaptureStackEnabled() bool {
+	return captureStack.Load()
+}
+
+// Stack is a captured call stack
+type Stack []uintptr
+
+// callers captures the call stack, skipping the given number of frames
+func callers(skip int) Stack {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(skip, pcs[:])
+
+	stack := make(Stack, n)
+	copy(stack, pcs[:n])
+
+	return stack
+}
+
+// Frames returns the frames of the stack
+func (s Stack) Frames() []runtime.Frame {
+	if len(s) == 0 {
+		return nil
+	}
+
+	frames := make([]runtime.Frame, 0, len(s))
+	iter := runtime.CallersFrames(s)
+	for {
+		frame, more := iter.Next()
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+
+	return frames
+}
+
+// Lines returns the stack as "function (file:line)" entries
+func (s Stack) Lines() []string {
+	frames := s.Frames()
+	lines := make([]string, 0, len(frames))
+	for _, frame := range frames {
+		lines = append(lines, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+	}
+
+	return lines
+}
+
+// String returns the stack in the same layout as runtime/debug.Stack
+func (s Stack) String() string {
+	var b strings.Builder
+	for i, frame := range s.Frames() {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+	}
+
+	return b.String()
+}
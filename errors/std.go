@@ -0,0 +1,39 @@
+package errors
+
+import (
+	stderrs "errors"
+)
+
+// Is reports whether any error in err's chain matches target (see errors.Is)
+func Is(err, target error) bool {
+	return stderrs.Is(err, target)
+}
+
+// As finds the first error in err's chain that matches target (see errors.As)
+func As(err error, target any) bool {
+	return stderrs.As(err, target)
+}
+
+// Unwrap returns the result of calling the Unwrap method on err (see errors.Unwrap)
+func Unwrap(err error) error {
+	return stderrs.Unwrap(err)
+}
+
+// From returns the first toolbox error in err's chain
+func From(err error) (*Error, bool) {
+	var tbErr *Error
+	if stderrs.As(err, &tbErr) {
+		return tbErr, true
+	}
+
+	return nil, false
+}
+
+// IsRetryable reports whether the first toolbox error in err's chain is retryable
+func IsRetryable(err error) bool {
+	if tbErr, ok := From(err); ok {
+		return tbErr.Retryable()
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expand replaces the named placeholders in the template with the params,
+// e.g. Expand("user {id} not found", map[string]any{"id": 42}) returns "user 42 not found".
+// Placeholders without a param are left as is.
+func Expand(template string, params map[string]any) string {
+	if len(params) == 0 || !strings.Contains(template, "{") {
+		return template
+	}
+
+	var b strings.Builder
+	b.Grow(len(template))
+
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		b.WriteString(template[:start])
+		if value, ok := params[template[start+1:end]]; ok {
+			fmt.Fprint(&b, value)
+		} else {
+			b.WriteString(template[start : end+1])
+		}
+		template = template[end+1:]
+	}
+	b.WriteString(template)
+
+	return b.String()
+}
+
+// Placeholders returns the names of the placeholders in the template in order of appearance
+func Placeholders(template string) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		name := template[start+1 : end]
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+		template = template[end+1:]
+	}
+
+	return names
+}
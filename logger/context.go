@@ -0,0 +1,26 @@
+package logger
+
+import "context"
+
+// RequestIDKey - key of the request id in the gin context and in the context values
+const RequestIDKey = "request_id"
+
+// requestIDKey - context key of the request id
+type requestIDKey struct{}
+
+// WithRequestID - store the request id in the context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext - get the request id of the context.
+// A gin context is supported too, the id is read from its keys.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	if requestID, ok := ctx.Value(requestIDKey{}).(string); ok && requestID != "" {
+		return requestID, true
+	}
+
+	requestID, ok := ctx.Value(RequestIDKey).(string)
+
+	return requestID, ok && requestID != ""
+}
@@ -8,7 +8,9 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/rlapenok/toolbox/errors"
 	"github.com/rlapenok/toolbox/logger"
+	"github.com/rlapenok/toolbox/routine"
 	"go.uber.org/zap"
 )
 
@@ -77,16 +79,24 @@ func (s *MicroService) Run(ctx context.Context) {
 
 	s.logger.Info("starting microservice...")
 
-	errChan := make(chan error, 1)
+	errChan := make(chan error, len(s.gracefulls))
 
 	for _, gracefull := range s.gracefulls {
-		go func() {
+		done := routine.Go(ctx, s.logger, func(context.Context) error {
 			s.logger.Info("starting gracefull",
 				zap.String("name", gracefull.Name()),
 				zap.String("address", gracefull.Address()),
 			)
 
 			if err := gracefull.Start(); err != nil && err != http.ErrServerClosed {
+				return err
+			}
+
+			return nil
+		})
+
+		go func() {
+			if err := <-done; err != nil {
 				s.logger.Error("failed to start gracefull",
 					zap.String("name", gracefull.Name()),
 					zap.String("address", gracefull.Address()),
@@ -100,38 +110,53 @@ func (s *MicroService) Run(ctx context.Context) {
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 
+	var stopErr error
+
 	select {
 	//TODO: add graceful shutdown
 	case <-ctx.Done():
 	case <-errChan:
-		for _, gracefull := range s.gracefulls {
-			if err := gracefull.Stop(ctx); err != nil {
-				s.logger.Error("failed to stop gracefull",
-					zap.String("name", gracefull.Name()),
-					zap.String("address", gracefull.Address()),
-					zap.Error(err),
-				)
-			}
-		}
+		stopErr = s.stop(ctx)
 	case <-signals:
 		s.logger.Info("received shutdown signal")
-		for _, gracefull := range s.gracefulls {
-			if err := gracefull.Stop(context.Background()); err != nil {
-				s.logger.Error("failed to stop gracefull",
-					zap.String("name", gracefull.Name()),
-					zap.String("address", gracefull.Address()),
-					zap.Error(err),
-				)
-			}
+		stopErr = s.stop(context.Background())
+	}
+
+	if stopErr != nil {
+		s.logger.Error("failed to stop microservice", zap.Error(stopErr))
+	}
+
+	s.logger.Info("microservice stopped")
+
+	s.logger.Sync()
+}
 
-			s.logger.Info("gracefull stopped",
+// stop - stop all gracefulls and aggregate their errors
+func (s *MicroService) stop(ctx context.Context) error {
+	var stopErr *errors.Error
+
+	for _, gracefull := range s.gracefulls {
+		if err := gracefull.Stop(ctx); err != nil {
+			s.logger.Error("failed to stop gracefull",
 				zap.String("name", gracefull.Name()),
 				zap.String("address", gracefull.Address()),
+				zap.Error(err),
 			)
+
+			stopErr = errors.Append(stopErr, err)
+
+			continue
 		}
+
+		s.logger.Info("gracefull stopped",
+			zap.String("name", gracefull.Name()),
+			zap.String("address", gracefull.Address()),
+		)
 	}
 
-	s.logger.Info("microservice stopped")
+	if stopErr == nil {
+		return nil
+	}
 
-	s.logger.Sync()
+	return stopErr
 }
@@ -0,0 +1,48 @@
+// Package routine - panic-safe goroutines
+package routine
+
+import (
+	"context"
+
+	"github.com/rlapenok/toolbox/errors"
+	"go.uber.org/zap"
+)
+
+// Func - function run by Go and Run
+type Func func(ctx context.Context) error
+
+// Go - run fn in a new goroutine, recovering its panic into an Internal toolbox error.
+// The result of fn is sent to the returned channel, which is closed afterwards.
+// Panics are logged with their stack.
+func Go(ctx context.Context, logger *zap.Logger, fn Func) <-chan error {
+	result := make(chan error, 1)
+
+	go func() {
+		defer close(result)
+
+		result <- Run(ctx, logger, fn)
+	}()
+
+	return result
+}
+
+// Run - run fn in the current goroutine, recovering its panic into an Internal toolbox error.
+// Panics are logged with their stack.
+func Run(ctx context.Context, logger *zap.Logger, fn Func) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr := errors.FromPanic(r)
+
+			if logger != nil {
+				logger.Error("PANIC",
+					zap.Any("panic", r),
+					zap.Strings("stacktrace", panicErr.StackTrace()),
+				)
+			}
+
+			err = panicErr
+		}
+	}()
+
+	return fn(ctx)
+}
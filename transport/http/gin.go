@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	middlewareHTTP "github.com/rlapenok/toolbox/transport/middleware/http"
 	"go.uber.org/zap"
 )
 
@@ -31,6 +32,12 @@ func NewGinServer(config Config, wrapper EngineWrapper) *GinServer {
 
 	engine := gin.New()
 
+	// the error middlewares redact errors by the environment of the service
+	environment := config.GetEnvironment()
+	engine.Use(func(c *gin.Context) {
+		c.Set(middlewareHTTP.EnvironmentKey, environment)
+	})
+
 	if wrapper != nil {
 		wrapper(engine)
 	}
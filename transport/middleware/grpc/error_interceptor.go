@@ -0,0 +1,50 @@
+// Package grpc - middlewares for GRPC servers
+package grpc
+
+import (
+	"context"
+
+	"github.com/rlapenok/toolbox/errors"
+	"google.golang.org/grpc"
+)
+
+// ErrorUnaryServerInterceptor - interceptor that converts toolbox errors into GRPC statuses
+// with the locale message negotiated from the "accept-language" metadata
+func ErrorUnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, o.toStatusError(ctx, err)
+		}
+
+		return resp, nil
+	}
+}
+
+// ErrorStreamServerInterceptor - interceptor that converts toolbox errors into GRPC statuses
+// with the locale message negotiated from the "accept-language" metadata
+func ErrorStreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return o.toStatusError(ss.Context(), err)
+		}
+
+		return nil
+	}
+}
+
+// toStatusError - convert the toolbox error into a GRPC status error, other errors are returned as is
+func (o *options) toStatusError(ctx context.Context, err error) error {
+	toolboxError, ok := errors.From(err)
+	if !ok {
+		return err
+	}
+
+	preferred := errors.AcceptLanguageFromIncomingContext(ctx)
+
+	return o.prepare(toolboxError).ToLocalizedGRPCStatus(preferred...).Err()
+}
@@ -0,0 +1,46 @@
+package grpc
+
+import "github.com/rlapenok/toolbox/errors"
+
+// options - settings of the error interceptors
+type options struct {
+	redact bool
+}
+
+// Option - option of the error interceptors
+type Option func(*options)
+
+// WithRedaction - strip causes and server error messages before sending
+func WithRedaction(redact bool) Option {
+	return func(o *options) {
+		o.redact = redact
+	}
+}
+
+// WithEnvironment - enable redaction only in the production environment ("production" or "prod")
+func WithEnvironment(environment string) Option {
+	return WithRedaction(errors.IsProduction(environment))
+}
+
+// newOptions - apply options over the defaults
+func newOptions(opts []Option) *options {
+	// errors are redacted unless the environment is known not to be production
+	o := &options{
+		redact: true,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// prepare - redact the error if required before sending
+func (o *options) prepare(err *errors.Error) *errors.Error {
+	if o.redact {
+		return err.Redacted()
+	}
+
+	return err
+}
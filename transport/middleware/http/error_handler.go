@@ -5,8 +5,11 @@ import (
 	"github.com/rlapenok/toolbox/errors"
 )
 
-// ErrorHandlerMiddleware - middleware for handling errors
-func ErrorHandlerMiddleware() gin.HandlerFunc {
+// ErrorHandlerMiddleware - middleware for handling errors.
+// Internal details are never rendered, they are logged by LoggerMiddleware.
+func ErrorHandlerMiddleware(opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts)
+
 	return func(c *gin.Context) {
 
 		// call the next middleware
@@ -21,27 +24,36 @@ func ErrorHandlerMiddleware() gin.HandlerFunc {
 		}
 
 		// try to cast the error to a toolbox error
-		toolboxError, ok := ginError.Err.(*errors.Error)
+		toolboxError, ok := errors.From(ginError.Err)
+		if !ok {
+			// binding and validation errors are client errors
+			toolboxError, ok = bindingError(ginError)
+		}
 		if !ok {
-			// if the error is not a toolbox error, create a new toolbox error
-			message := ginError.Err.Error()
+			// if the error is not a toolbox error, create a new toolbox error,
+			// its text is kept in the internal details
 			details := errors.NewDetails()
-			details.WithLocaleMessage("en-EN", message)
+			details.WithLocaleMessage("en-EN", "internal server error")
 
-			toolboxError = errors.New(errors.Internal, message).WithDetails(details)
+			toolboxError = errors.Wrap(ginError.Err, errors.Internal, "internal server error").
+				WithDetails(details).
+				WithInternalDetails(map[string]any{"error": ginError.Err.Error()})
 		}
 
-		httpStatus := toolboxError.ToHTTPStatus()
-		code := toolboxError.Code()
-		message := toolboxError.Message()
-		details := toolboxError.Details()
-		reason := toolboxError.Reason()
-
-		c.AbortWithStatusJSON(httpStatus, gin.H{
-			"code":    code,
-			"message": message,
-			"reason":  reason,
-			"details": details,
-		})
+		setRetryAfter(c, toolboxError)
+
+		o.renderer.Render(c, o.prepare(c, toolboxError))
+	}
+}
+
+// setRetryAfter - set the Retry-After header for throttled and unavailable errors that carry a delay
+func setRetryAfter(c *gin.Context, err *errors.Error) {
+	if err.RetryAfter() <= 0 {
+		return
+	}
+
+	switch err.Code() {
+	case errors.TooManyRequests, errors.Unavailable:
+		c.Header("Retry-After", errors.RetryAfterHeader(err.RetryAfter()))
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rlapenok/toolbox/errors"
 	"go.uber.org/zap"
 )
 
@@ -68,7 +69,20 @@ func LoggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
 
 		// add error to log if present
 		if len(c.Errors) > 0 && c.Errors.Last() != nil {
-			logFunc(message, zap.Error(c.Errors.Last().Err))
+			err := c.Errors.Last().Err
+			fields := []zap.Field{zap.Error(err)}
+
+			// add the internal details and the stack of the toolbox error
+			if toolboxError, ok := errors.From(err); ok {
+				if internalDetails := toolboxError.InternalDetails(); internalDetails != nil {
+					fields = append(fields, zap.Any("internal_details", internalDetails))
+				}
+				if stack := toolboxError.StackTrace(); len(stack) > 0 {
+					fields = append(fields, zap.Strings("stacktrace", stack))
+				}
+			}
+
+			logFunc(message, fields...)
 		} else {
 			logFunc(message)
 		}
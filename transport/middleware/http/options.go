@@ -0,0 +1,75 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rlapenok/toolbox/errors"
+)
+
+// EnvironmentKey - key of the environment of the service in the gin context, set by GinServer
+const EnvironmentKey = "environment"
+
+// options - settings of the error rendering middlewares
+type options struct {
+	renderer Renderer
+	// redact - explicit redaction, nil derives it from the environment of the request
+	redact *bool
+}
+
+// Option - option of the error rendering middlewares
+type Option func(*options)
+
+// WithRenderer - set the renderer of the error body
+func WithRenderer(renderer Renderer) Option {
+	return func(o *options) {
+		o.renderer = renderer
+	}
+}
+
+// WithRedaction - strip causes and server error messages before rendering
+func WithRedaction(redact bool) Option {
+	return func(o *options) {
+		o.redact = &redact
+	}
+}
+
+// WithEnvironment - enable redaction only in the production environment ("production" or "prod").
+// Without WithRedaction or WithEnvironment the environment set by GinServer is used,
+// errors are redacted if it is unknown.
+func WithEnvironment(environment string) Option {
+	return WithRedaction(errors.IsProduction(environment))
+}
+
+// newOptions - apply options over the defaults
+func newOptions(opts []Option) *options {
+	o := &options{
+		renderer: ToolboxRenderer(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// prepare - redact the error if required before rendering
+func (o *options) prepare(c *gin.Context, err *errors.Error) *errors.Error {
+	if o.redacts(c) {
+		return err.Redacted()
+	}
+
+	return err
+}
+
+// redacts - check if the errors of the request must be redacted
+func (o *options) redacts(c *gin.Context) bool {
+	if o.redact != nil {
+		return *o.redact
+	}
+
+	if environment := c.GetString(EnvironmentKey); environment != "" {
+		return errors.IsProduction(environment)
+	}
+
+	return true
+}
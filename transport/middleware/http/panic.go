@@ -7,28 +7,21 @@ import (
 )
 
 // PanicMiddleware - middleware для обработки паники
-func PanicMiddleware(logger *zap.Logger) gin.HandlerFunc {
+func PanicMiddleware(logger *zap.Logger, opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts)
+
 	return func(c *gin.Context) {
 
 		defer func() {
 			if r := recover(); r != nil {
-				err := errors.New(errors.Internal, "internal server error").
-					WithDetails(r)
-
-				httpStatus := err.ToHTTPStatus()
-				code := err.Code()
-				message := err.Message()
-				reason := err.Reason()
-				details := err.Details()
+				err := errors.FromPanic(r)
 
-				logger.Error("PANIC", zap.Any("panic", r))
+				logger.Error("PANIC",
+					zap.Any("panic", r),
+					zap.Strings("stacktrace", err.StackTrace()),
+				)
 
-				c.AbortWithStatusJSON(httpStatus, gin.H{
-					"code":    code,
-					"message": message,
-					"reason":  reason,
-					"details": details,
-				})
+				o.renderer.Render(c, o.prepare(c, err))
 			}
 		}()
 
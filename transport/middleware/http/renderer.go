@@ -0,0 +1,190 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rlapenok/toolbox/errors"
+)
+
+const (
+	// ContentTypeJSON - media type of the toolbox error body
+	ContentTypeJSON = "application/json"
+	// ContentTypeProblemJSON - media type of the RFC 9457 problem details body
+	ContentTypeProblemJSON = "application/problem+json"
+
+	// problemTypeBlank - default problem type when the type is not specified
+	problemTypeBlank = "about:blank"
+)
+
+// Renderer - renders a toolbox error into the response
+type Renderer interface {
+	// ContentType - returns the media type of the rendered body
+	ContentType() string
+	// Render - writes the error to the response and aborts the request
+	Render(c *gin.Context, err *errors.Error)
+}
+
+// toolboxRenderer - renders errors as {code, message, reason, details}
+type toolboxRenderer struct{}
+
+// ToolboxRenderer - renderer for the toolbox error shape {code, message, reason, details}
+func ToolboxRenderer() Renderer {
+	return toolboxRenderer{}
+}
+
+// ContentType - returns the media type of the rendered body
+func (toolboxRenderer) ContentType() string {
+	return ContentTypeJSON
+}
+
+// Render - writes the error to the response and aborts the request
+func (toolboxRenderer) Render(c *gin.Context, err *errors.Error) {
+	body := gin.H{
+		"code":      err.Code(),
+		"message":   err.Message(),
+		"reason":    err.Reason(),
+		"retryable": err.Retryable(),
+		"details":   err.Details(),
+	}
+
+	if retryAfter := err.RetryAfter(); retryAfter > 0 {
+		body["retry_after"] = retryAfter.Seconds()
+	}
+
+	if localized := localizedMessage(c, err); localized != nil {
+		body["localized_message"] = localized
+	}
+
+	c.AbortWithStatusJSON(err.ToHTTPStatus(), body)
+}
+
+// problemRenderer - renders errors as RFC 9457 problem details
+type problemRenderer struct {
+	typeBaseURI string
+}
+
+// ProblemRenderer - renderer for RFC 9457 problem details (application/problem+json).
+// If typeBaseURI is not empty, the problem type is typeBaseURI joined with the error reason,
+// otherwise the type is "about:blank".
+// The toolbox code, reason and details are sent as extension members.
+func ProblemRenderer(typeBaseURI string) Renderer {
+	return problemRenderer{typeBaseURI: strings.TrimSuffix(typeBaseURI, "/")}
+}
+
+// ContentType - returns the media type of the rendered body
+func (problemRenderer) ContentType() string {
+	return ContentTypeProblemJSON
+}
+
+// Render - writes the error to the response and aborts the request
+func (r problemRenderer) Render(c *gin.Context, err *errors.Error) {
+	httpStatus := err.ToHTTPStatus()
+
+	problemType := problemTypeBlank
+	if r.typeBaseURI != "" && err.Reason() != "" {
+		problemType = r.typeBaseURI + "/" + string(err.Reason())
+	}
+
+	title := http.StatusText(httpStatus)
+	if title == "" {
+		title = err.Code().String()
+	}
+
+	body := gin.H{
+		"type":     problemType,
+		"title":    title,
+		"status":   httpStatus,
+		"detail":   err.Message(),
+		"instance": c.Request.URL.Path,
+
+		// extension members
+		"code":      err.Code(),
+		"reason":    err.Reason(),
+		"retryable": err.Retryable(),
+		"details":   err.Details(),
+	}
+
+	if retryAfter := err.RetryAfter(); retryAfter > 0 {
+		body["retry_after"] = retryAfter.Seconds()
+	}
+
+	if requestID := c.GetString("request_id"); requestID != "" {
+		body["request_id"] = requestID
+	}
+
+	if localized := localizedMessage(c, err); localized != nil {
+		body["localized_message"] = localized
+	}
+
+	// render.JSON keeps the content type if it is already set
+	c.Header("Content-Type", ContentTypeProblemJSON+"; charset=utf-8")
+	c.AbortWithStatusJSON(httpStatus, body)
+}
+
+// negotiatedRenderer - chooses a renderer by the Accept header
+type negotiatedRenderer struct {
+	renderers    []Renderer
+	contentTypes []string
+}
+
+// NegotiatedRenderer - renderer that chooses one of the renderers by the Accept header.
+// The first renderer is used when the client does not ask for a specific media type.
+func NegotiatedRenderer(renderers ...Renderer) Renderer {
+	if len(renderers) == 0 {
+		renderers = []Renderer{ToolboxRenderer()}
+	}
+
+	contentTypes := make([]string, 0, len(renderers))
+	for _, renderer := range renderers {
+		contentTypes = append(contentTypes, renderer.ContentType())
+	}
+
+	return negotiatedRenderer{
+		renderers:    renderers,
+		contentTypes: contentTypes,
+	}
+}
+
+// ContentType - returns the media type of the default renderer
+func (r negotiatedRenderer) ContentType() string {
+	return r.contentTypes[0]
+}
+
+// Render - writes the error with the negotiated renderer
+func (r negotiatedRenderer) Render(c *gin.Context, err *errors.Error) {
+	contentType := c.NegotiateFormat(r.contentTypes...)
+
+	for i, renderer := range r.renderers {
+		if r.contentTypes[i] == contentType {
+			renderer.Render(c, err)
+			return
+		}
+	}
+
+	r.renderers[0].Render(c, err)
+}
+
+// localizedMessage - negotiate the locale message of the error by the Accept-Language header
+func localizedMessage(c *gin.Context, err *errors.Error) gin.H {
+	details, ok := err.Details().(*errors.Details)
+	if !ok {
+		return nil
+	}
+
+	c.Writer.Header().Add("Vary", "Accept-Language")
+
+	preferred := errors.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+	locale, message, ok := details.Localize(preferred...)
+	if !ok {
+		return nil
+	}
+
+	c.Header("Content-Language", locale)
+
+	return gin.H{
+		"locale":  locale,
+		"message": message,
+	}
+}
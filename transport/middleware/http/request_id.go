@@ -3,9 +3,10 @@ package http
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/rlapenok/toolbox/logger"
 )
 
-// RequestIDMiddleware - middleware для генерации и установки request_id в заголовке
+// RequestIDMiddleware - middleware для генерации и установки request_id в заголовке и в контексте запроса
 func RequestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
@@ -14,7 +15,8 @@ func RequestIDMiddleware() gin.HandlerFunc {
 			requestID = uuid.New().String()
 		}
 
-		c.Set("request_id", requestID)
+		c.Set(logger.RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
 		c.Writer.Header().Set("X-Request-ID", requestID)
 
 		c.Next()
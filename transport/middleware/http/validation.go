@@ -0,0 +1,219 @@
+package http
+
+import (
+	"encoding/json"
+	stderrs "errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/rlapenok/toolbox/errors"
+)
+
+// validationLocale - locale of the built-in validation messages
+const validationLocale = "en"
+
+var (
+	// validationMessagesMu - guards validationMessages
+	validationMessagesMu sync.RWMutex
+
+	// validationMessages - templates of the field violation descriptions keyed by locale and rule.
+	// {field} and {param} are replaced with the field path and the rule parameter.
+	validationMessages = map[string]map[string]string{
+		validationLocale: {
+			"required": "{field} is required",
+			"email":    "{field} must be a valid email address",
+			"url":      "{field} must be a valid URL",
+			"uuid":     "{field} must be a valid UUID",
+			"min":      "{field} must be at least {param}",
+			"max":      "{field} must be at most {param}",
+			"len":      "{field} must have length {param}",
+			"eq":       "{field} must be equal to {param}",
+			"ne":       "{field} must not be equal to {param}",
+			"gt":       "{field} must be greater than {param}",
+			"gte":      "{field} must be greater than or equal to {param}",
+			"lt":       "{field} must be less than {param}",
+			"lte":      "{field} must be less than or equal to {param}",
+			"oneof":    "{field} must be one of [{param}]",
+			"type":     "{field} must be of type {param}",
+		},
+	}
+)
+
+// RegisterValidationMessages - register description templates of the validation rules for the locale.
+// {field} and {param} in a template are replaced with the field path and the rule parameter.
+func RegisterValidationMessages(locale string, messages map[string]string) {
+	validationMessagesMu.Lock()
+	defer validationMessagesMu.Unlock()
+
+	if validationMessages[locale] == nil {
+		validationMessages[locale] = make(map[string]string, len(messages))
+	}
+	for rule, template := range messages {
+		validationMessages[locale][rule] = template
+	}
+}
+
+// UseJSONFieldNames - make the gin validator report field paths by their json tags instead of Go names
+func UseJSONFieldNames() {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return field.Name
+		default:
+			return name
+		}
+	})
+}
+
+// BindingError - convert a gin binding or validation error into a toolbox error:
+// BadRequest with field violations for validation errors, InvalidParameter for a body
+// or a parameter that can't be decoded. It returns false if err is not caused by binding the request.
+func BindingError(err error) (*errors.Error, bool) {
+	if toolboxError, ok := validationError(err); ok {
+		return toolboxError, true
+	}
+	if toolboxError, ok := decodeError(err); ok {
+		return toolboxError, true
+	}
+
+	var numError *strconv.NumError
+	if stderrs.As(err, &numError) {
+		return errors.Wrap(err, errors.InvalidParameter, err.Error()).
+			WithReason(errors.ReasonBadRequest), true
+	}
+
+	return nil, false
+}
+
+// decodeError - convert a malformed, mistyped, empty or truncated JSON body into an InvalidParameter toolbox error
+func decodeError(err error) (*errors.Error, bool) {
+	var (
+		syntaxError *json.SyntaxError
+		typeError   *json.UnmarshalTypeError
+	)
+
+	details := errors.NewDetails()
+	message := err.Error()
+
+	switch {
+	case stderrs.As(err, &typeError):
+		details.WithViolation(newViolation(typeError.Field, "type", typeError.Type.String()))
+	case stderrs.As(err, &syntaxError):
+	case stderrs.Is(err, io.EOF), stderrs.Is(err, io.ErrUnexpectedEOF):
+		message = "request body is empty or truncated"
+	default:
+		return nil, false
+	}
+
+	return errors.Wrap(err, errors.InvalidParameter, message).
+		WithReason(errors.ReasonBadRequest).
+		WithDetails(details), true
+}
+
+// validationError - convert validator errors into a BadRequest toolbox error with field violations
+func validationError(err error) (*errors.Error, bool) {
+	var (
+		validationErrors validator.ValidationErrors
+		sliceErrors      binding.SliceValidationError
+	)
+
+	details := errors.NewDetails()
+
+	switch {
+	case stderrs.As(err, &validationErrors):
+		addFieldErrors(details, validationErrors)
+	case stderrs.As(err, &sliceErrors):
+		for _, itemErr := range sliceErrors {
+			var itemValidationErrors validator.ValidationErrors
+			if stderrs.As(itemErr, &itemValidationErrors) {
+				addFieldErrors(details, itemValidationErrors)
+			}
+		}
+	default:
+		return nil, false
+	}
+
+	return errors.Wrap(err, errors.BadRequest, "request validation failed").
+		WithReason(errors.ReasonBadRequest).
+		WithDetails(details), true
+}
+
+// bindingError - convert the gin error into a toolbox error if it was caused by binding the request
+func bindingError(ginError *gin.Error) (*errors.Error, bool) {
+	// c.Bind and c.MustBindWith mark their errors with ErrorTypeBind
+	if ginError.IsType(gin.ErrorTypeBind) {
+		if toolboxError, ok := BindingError(ginError.Err); ok {
+			return toolboxError, true
+		}
+
+		return errors.Wrap(ginError.Err, errors.InvalidParameter, ginError.Err.Error()).
+			WithReason(errors.ReasonBadRequest), true
+	}
+
+	// validation and JSON decoding errors of c.ShouldBind added by the handler
+	if toolboxError, ok := validationError(ginError.Err); ok {
+		return toolboxError, true
+	}
+
+	return decodeError(ginError.Err)
+}
+
+// addFieldErrors - add a field violation for each failed validation rule
+func addFieldErrors(details *errors.Details, fieldErrors validator.ValidationErrors) {
+	for _, fieldError := range fieldErrors {
+		details.WithViolation(newViolation(fieldPath(fieldError), fieldError.Tag(), fieldError.Param()))
+	}
+}
+
+// newViolation - create a field violation with descriptions in every registered locale
+func newViolation(field, rule, param string) *errors.FieldViolation {
+	validationMessagesMu.RLock()
+	defer validationMessagesMu.RUnlock()
+
+	description := describeViolation(validationMessages[validationLocale], field, rule, param)
+	violation := errors.NewFieldViolation(field, description).
+		WithRule(rule, param).
+		WithLocaleDescription(validationLocale, description)
+
+	for locale, messages := range validationMessages {
+		if _, ok := messages[rule]; ok && locale != validationLocale {
+			violation.WithLocaleDescription(locale, describeViolation(messages, field, rule, param))
+		}
+	}
+
+	return violation
+}
+
+// describeViolation - render the template of the rule, falling back to a generic description
+func describeViolation(messages map[string]string, field, rule, param string) string {
+	template, ok := messages[rule]
+	if !ok {
+		template = "{field} failed on the '" + rule + "' rule"
+	}
+
+	return strings.NewReplacer("{field}", field, "{param}", param).Replace(template)
+}
+
+// fieldPath - return the path of the field without the name of the top-level struct
+func fieldPath(fieldError validator.FieldError) string {
+	namespace := fieldError.Namespace()
+	if i := strings.IndexByte(namespace, '.'); i >= 0 {
+		return namespace[i+1:]
+	}
+
+	return namespace
+}